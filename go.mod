@@ -2,10 +2,16 @@ module github.com/dougalmatthews/glug
 
 go 1.25.0
 
-require github.com/fatih/color v1.19.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/fatih/color v1.19.0
+	github.com/mattn/go-isatty v0.0.24
+	github.com/mattn/go-runewidth v0.0.16
+	golang.org/x/term v0.41.0
+)
 
 require (
 	github.com/mattn/go-colorable v0.1.15 // indirect
-	github.com/mattn/go-isatty v0.0.24 // indirect
+	github.com/rivo/uniseg v0.2.0 // indirect
 	golang.org/x/sys v0.42.0 // indirect
 )
@@ -0,0 +1,287 @@
+// Package config loads glug's TOML configuration file and merges it with
+// the options given on the command line.
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// EnvVar is the environment variable naming the configuration file
+const EnvVar = "GLUG_CONFIG"
+
+// File is the contents of a configuration file: top-level settings and the
+// named profiles that can be layered on top of them
+type File struct {
+	Settings
+	// Profiles are named bundles of settings, chosen with --profile or by
+	// their match expression
+	Profiles map[string]Profile `toml:"profiles,omitempty"`
+	// Themes are user-defined themes, chosen with --theme or the theme
+	// setting. Each maps theme roles to style specs, with an optional "base"
+	// naming the built-in theme the other roles come from.
+	Themes map[string]map[string]string `toml:"themes,omitempty"`
+}
+
+// Profile is a named set of settings. A profile with a match expression is
+// applied automatically to the entries the expression matches.
+type Profile struct {
+	// Match is a --where expression selecting the entries the profile applies to
+	Match string `toml:"match,omitempty"`
+	Settings
+}
+
+// Settings holds the options that can be set in a configuration file
+type Settings struct {
+	// Level is the minimum log level to show
+	Level string `toml:"level,omitempty"`
+	// Colors are color rules in the same color:word form as --colour
+	Colors []string `toml:"colors,omitempty"`
+	// Highlight lists highlight rules in the same form as --highlight
+	Highlight []string `toml:"highlight,omitempty"`
+	// FieldColors are field color rules in the same form as --colour-field
+	FieldColors []string `toml:"field_colors,omitempty"`
+	// HashColors lists fields whose values each get a color of their own
+	HashColors []string `toml:"hash_colors,omitempty"`
+	// TimestampFields lists fields to convert to human-readable dates; auto
+	// converts the fields that look like timestamps
+	TimestampFields []string `toml:"timestamp_fields,omitempty"`
+	// TimestampExclude lists fields never converted by auto
+	TimestampExclude []string `toml:"timestamp_exclude,omitempty"`
+	// Pager enables or disables the pager
+	Pager *bool `toml:"pager,omitempty"`
+	// PagerCmd is the pager command line
+	PagerCmd string `toml:"pager_cmd,omitempty"`
+	// LevelScheme selects how numeric levels are decoded
+	LevelScheme string `toml:"level_scheme,omitempty"`
+	// Where is a field expression entries must match to be shown
+	Where string `toml:"where,omitempty"`
+	// ModulePaths marks stack trace frames from your own code
+	ModulePaths []string `toml:"module_paths,omitempty"`
+	// Hide lists fields that are never shown
+	Hide []string `toml:"hide,omitempty"`
+	// Fields overrides the keys holding the level, time and message
+	Fields Fields `toml:"fields,omitempty"`
+	// Theme names the built-in or user-defined theme to color output with
+	Theme string `toml:"theme,omitempty"`
+	// ColorMode is auto, always or never
+	ColorMode string `toml:"color_mode,omitempty"`
+	// TimeZone is the zone times are shown in: UTC, Local or an IANA name
+	TimeZone string `toml:"tz,omitempty"`
+	// TimeFormat is a preset name, strftime format or Go layout
+	TimeFormat string `toml:"time_format,omitempty"`
+	// TimePrecision is s, ms, us or ns
+	TimePrecision string `toml:"time_precision,omitempty"`
+	// TimeLayouts are extra Go layouts or strftime formats to parse times with
+	TimeLayouts []string `toml:"time_layouts,omitempty"`
+}
+
+// Fields lists the keys holding the level, time and message of an entry
+type Fields struct {
+	Level   []string `toml:"level,omitempty"`
+	Time    []string `toml:"time,omitempty"`
+	Message []string `toml:"message,omitempty"`
+}
+
+// Find returns the path of the configuration file to load. An explicit path
+// (from --config) is used first, then $GLUG_CONFIG, then glug/config.toml
+// in $XDG_CONFIG_HOME (default ~/.config) and $XDG_CONFIG_DIRS (default
+// /etc/xdg). It returns an empty path when no file exists; an explicit or
+// $GLUG_CONFIG path that does not exist is an error.
+func Find(explicit string) (string, error) {
+	for _, path := range []string{explicit, os.Getenv(EnvVar)} {
+		if path == "" {
+			continue
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("config file %s: %v", path, err)
+		}
+
+		return path, nil
+	}
+
+	for _, dir := range searchDirs() {
+		path := filepath.Join(dir, "glug", "config.toml")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", nil
+}
+
+// searchDirs returns the XDG configuration directories in order of preference
+func searchDirs() []string {
+	var dirs []string
+
+	if home := os.Getenv("XDG_CONFIG_HOME"); home != "" {
+		dirs = append(dirs, home)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+
+	configDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if configDirs == "" {
+		configDirs = "/etc/xdg"
+	}
+
+	for _, dir := range filepath.SplitList(configDirs) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+// Load reads a configuration file. An empty path returns an empty File.
+func Load(path string) (*File, error) {
+	file := &File{}
+	if path == "" {
+		return file, nil
+	}
+
+	meta, err := toml.DecodeFile(path, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+
+		return nil, fmt.Errorf("unknown keys in config file %s: %s", path, strings.Join(keys, ", "))
+	}
+
+	return file, nil
+}
+
+// Profile returns the settings of the named profile
+func (f *File) Profile(name string) (*Settings, error) {
+	profile, ok := f.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+
+	return &profile.Settings, nil
+}
+
+// ProfileNames returns the names of the profiles, sorted so that automatic
+// selection tries them in a predictable order
+func (f *File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Merge returns a copy of s with the settings in override applied on top.
+// Override values replace those of s when set, except color rules, which are
+// appended so that later rules win for the same word, hidden fields, which
+// are combined, and where expressions, which must both match.
+func (s *Settings) Merge(override *Settings) *Settings {
+	merged := *s
+
+	if override.Level != "" {
+		merged.Level = override.Level
+	}
+
+	merged.Colors = append(append([]string(nil), s.Colors...), override.Colors...)
+	merged.Highlight = append(append([]string(nil), s.Highlight...), override.Highlight...)
+	merged.FieldColors = append(append([]string(nil), s.FieldColors...), override.FieldColors...)
+	merged.HashColors = append(append([]string(nil), s.HashColors...), override.HashColors...)
+	merged.TimeLayouts = append(append([]string(nil), s.TimeLayouts...), override.TimeLayouts...)
+	merged.TimestampExclude = append(append([]string(nil), s.TimestampExclude...), override.TimestampExclude...)
+	merged.Hide = append(append([]string(nil), s.Hide...), override.Hide...)
+
+	if len(override.TimestampFields) > 0 {
+		merged.TimestampFields = override.TimestampFields
+	}
+
+	if override.Pager != nil {
+		merged.Pager = override.Pager
+	}
+
+	if override.PagerCmd != "" {
+		merged.PagerCmd = override.PagerCmd
+	}
+
+	if override.LevelScheme != "" {
+		merged.LevelScheme = override.LevelScheme
+	}
+
+	if len(override.ModulePaths) > 0 {
+		merged.ModulePaths = override.ModulePaths
+	}
+
+	if override.Theme != "" {
+		merged.Theme = override.Theme
+	}
+
+	if override.ColorMode != "" {
+		merged.ColorMode = override.ColorMode
+	}
+
+	if override.TimeZone != "" {
+		merged.TimeZone = override.TimeZone
+	}
+
+	if override.TimeFormat != "" {
+		merged.TimeFormat = override.TimeFormat
+	}
+
+	if override.TimePrecision != "" {
+		merged.TimePrecision = override.TimePrecision
+	}
+
+	switch {
+	case s.Where == "":
+		merged.Where = override.Where
+	case override.Where != "":
+		merged.Where = "(" + s.Where + ") && (" + override.Where + ")"
+	}
+
+	if len(override.Fields.Level) > 0 {
+		merged.Fields.Level = override.Fields.Level
+	}
+
+	if len(override.Fields.Time) > 0 {
+		merged.Fields.Time = override.Fields.Time
+	}
+
+	if len(override.Fields.Message) > 0 {
+		merged.Fields.Message = override.Fields.Message
+	}
+
+	return &merged
+}
+
+// UsePager reports whether the pager is enabled, defaulting to true
+func (s *Settings) UsePager() bool {
+	return s.Pager == nil || *s.Pager
+}
+
+// Write encodes the settings as TOML
+func (f *File) Write(w io.Writer) error {
+	encoder := toml.NewEncoder(w)
+	encoder.Indent = ""
+
+	if err := encoder.Encode(f); err != nil {
+		return fmt.Errorf("failed to encode config: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,319 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleConfig = `level = "info"
+colors = ["green:PASS", "red:FAIL"]
+timestamp_fields = ["expires", "validUntil"]
+pager = false
+pager_cmd = "less -S"
+level_scheme = "pino"
+module_paths = ["github.com/acme"]
+theme = "mine"
+
+[fields]
+message = ["textPayload"]
+
+[profiles.gateway]
+match = 'program == "gateway"'
+colors = ["red:502"]
+
+[themes.mine]
+base = "light"
+time = "#2aa198"
+`
+
+// writeConfig writes contents to a config file in dir and returns its path
+func writeConfig(t *testing.T, dir, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "glug", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), sampleConfig)
+
+	file, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if file.Level != "info" || file.PagerCmd != "less -S" || file.LevelScheme != "pino" {
+		t.Errorf("Load() scalar settings = %+v", file)
+	}
+
+	if !reflect.DeepEqual(file.Colors, []string{"green:PASS", "red:FAIL"}) {
+		t.Errorf("Load() colors = %v", file.Colors)
+	}
+
+	if !reflect.DeepEqual(file.TimestampFields, []string{"expires", "validUntil"}) {
+		t.Errorf("Load() timestamp fields = %v", file.TimestampFields)
+	}
+
+	if file.UsePager() {
+		t.Error("Load() pager = true, want false")
+	}
+
+	if !reflect.DeepEqual(file.Fields.Message, []string{"textPayload"}) {
+		t.Errorf("Load() message fields = %v", file.Fields.Message)
+	}
+
+	if !reflect.DeepEqual(file.ModulePaths, []string{"github.com/acme"}) {
+		t.Errorf("Load() module paths = %v", file.ModulePaths)
+	}
+
+	if file.Theme != "mine" || !reflect.DeepEqual(file.Themes["mine"], map[string]string{"base": "light", "time": "#2aa198"}) {
+		t.Errorf("Load() theme = %q, themes = %v", file.Theme, file.Themes)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{"invalid toml", "level = ", "failed to read config file"},
+		{"wrong type", "pager = \"yes\"", "failed to read config file"},
+		{"unknown key", "levle = \"info\"", "unknown keys in config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, t.TempDir(), tt.contents)
+
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	home := t.TempDir()
+	system := t.TempDir()
+	empty := t.TempDir()
+	homeConfig := writeConfig(t, home, "")
+	systemConfig := writeConfig(t, system, "")
+	explicit := filepath.Join(t.TempDir(), "custom.toml")
+
+	if err := os.WriteFile(explicit, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		explicit   string
+		env        string
+		configHome string
+		configDirs string
+		want       string
+		wantErr    bool
+	}{
+		{"explicit path wins", explicit, homeConfig, home, system, explicit, false},
+		{"environment variable", "", explicit, home, system, explicit, false},
+		{"config home", "", "", home, system, homeConfig, false},
+		{"config dirs", "", "", empty, empty + string(os.PathListSeparator) + system, systemConfig, false},
+		{"nothing found", "", "", empty, empty, "", false},
+		{"missing explicit path", filepath.Join(empty, "missing.toml"), "", home, system, "", true},
+		{"missing environment path", "", filepath.Join(empty, "missing.toml"), home, system, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvVar, tt.env)
+			t.Setenv("XDG_CONFIG_HOME", tt.configHome)
+			t.Setenv("XDG_CONFIG_DIRS", tt.configDirs)
+
+			got, err := Find(tt.explicit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Find(%q) error = %v, wantErr %v", tt.explicit, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Find(%q) = %q, want %q", tt.explicit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	off := false
+	on := true
+
+	settings := &Settings{
+		Level:            "info",
+		Colors:           []string{"green:PASS"},
+		Highlight:        []string{"word:ERROR"},
+		FieldColors:      []string{"status>=500:red"},
+		HashColors:       []string{"request_id"},
+		TimestampFields:  []string{"expires"},
+		TimestampExclude: []string{"deadline"},
+		Pager:            &off,
+		PagerCmd:         "less",
+		LevelScheme:      "syslog",
+		Hide:             []string{"password"},
+		Fields:           Fields{Level: []string{"severity"}, Message: []string{"textPayload"}},
+		Theme:            "light",
+		TimeZone:         "UTC",
+		TimeFormat:       "iso",
+		TimeLayouts:      []string{"%d/%m/%Y %H:%M"},
+	}
+
+	t.Run("empty override keeps file settings", func(t *testing.T) {
+		merged := settings.Merge(&Settings{})
+		if !reflect.DeepEqual(merged, settings) {
+			t.Errorf("Merge() = %+v, want %+v", merged, settings)
+		}
+	})
+
+	t.Run("override replaces set values", func(t *testing.T) {
+		merged := settings.Merge(&Settings{
+			Level:            "error",
+			Colors:           []string{"red:PASS"},
+			Highlight:        []string{`re:id=(\d+)`},
+			FieldColors:      []string{"user:cyan"},
+			HashColors:       []string{"pod"},
+			TimestampFields:  []string{"auto"},
+			TimestampExclude: []string{"since"},
+			Pager:            &on,
+			LevelScheme:      "bunyan",
+			Hide:             []string{"token"},
+			Fields:           Fields{Message: []string{"msg"}},
+			Theme:            "solarized",
+			ColorMode:        "never",
+			TimeFormat:       "relative",
+			TimePrecision:    "ms",
+			TimeLayouts:      []string{"02.01.2006"},
+		})
+
+		want := &Settings{
+			Level:            "error",
+			Colors:           []string{"green:PASS", "red:PASS"},
+			Highlight:        []string{"word:ERROR", `re:id=(\d+)`},
+			FieldColors:      []string{"status>=500:red", "user:cyan"},
+			HashColors:       []string{"request_id", "pod"},
+			TimestampFields:  []string{"auto"},
+			TimestampExclude: []string{"deadline", "since"},
+			Pager:            &on,
+			PagerCmd:         "less",
+			LevelScheme:      "bunyan",
+			Hide:             []string{"password", "token"},
+			Fields:           Fields{Level: []string{"severity"}, Message: []string{"msg"}},
+			Theme:            "solarized",
+			ColorMode:        "never",
+			TimeZone:         "UTC",
+			TimeFormat:       "relative",
+			TimePrecision:    "ms",
+			TimeLayouts:      []string{"%d/%m/%Y %H:%M", "02.01.2006"},
+		}
+
+		if !reflect.DeepEqual(merged, want) {
+			t.Errorf("Merge() = %+v, want %+v", merged, want)
+		}
+
+		if len(settings.Colors) != 1 {
+			t.Errorf("Merge() modified the original colors: %v", settings.Colors)
+		}
+	})
+
+	t.Run("where expressions must both match", func(t *testing.T) {
+		tests := []struct {
+			base, override, want string
+		}{
+			{"", "", ""},
+			{"status >= 500", "", "status >= 500"},
+			{"", "has(error)", "has(error)"},
+			{"status >= 500", "has(error)", "(status >= 500) && (has(error))"},
+		}
+
+		for _, tt := range tests {
+			merged := (&Settings{Where: tt.base}).Merge(&Settings{Where: tt.override})
+			if merged.Where != tt.want {
+				t.Errorf("Merge(%q, %q) where = %q, want %q", tt.base, tt.override, merged.Where, tt.want)
+			}
+		}
+	})
+}
+
+func TestProfiles(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), `level = "info"
+
+[profiles.gateway]
+match = 'program == "gateway"'
+colors = ["red:502"]
+
+[profiles.agent]
+timestamp_fields = ["validUntil"]
+where = 'subsystem == "secretstore"'
+`)
+
+	file, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if names := file.ProfileNames(); !reflect.DeepEqual(names, []string{"agent", "gateway"}) {
+		t.Errorf("ProfileNames() = %v, want [agent gateway]", names)
+	}
+
+	if match := file.Profiles["gateway"].Match; match != `program == "gateway"` {
+		t.Errorf("gateway match = %q", match)
+	}
+
+	agent, err := file.Profile("agent")
+	if err != nil {
+		t.Fatalf("Profile(agent) error: %v", err)
+	}
+
+	merged := file.Merge(agent)
+	if merged.Level != "info" || merged.Where != `subsystem == "secretstore"` || !reflect.DeepEqual(merged.TimestampFields, []string{"validUntil"}) {
+		t.Errorf("file merged with agent profile = %+v", merged)
+	}
+
+	if _, err := file.Profile("missing"); err == nil {
+		t.Error("Profile(missing) expected an error")
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), sampleConfig)
+
+	file, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := file.Write(&buf); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+
+	written := writeConfig(t, t.TempDir(), buf.String())
+
+	reloaded, err := Load(written)
+	if err != nil {
+		t.Fatalf("Load() of written config error: %v\n%s", err, buf.String())
+	}
+
+	if !reflect.DeepEqual(reloaded, file) {
+		t.Errorf("round trip = %+v, want %+v", reloaded, file)
+	}
+}
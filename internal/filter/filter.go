@@ -0,0 +1,472 @@
+// Package filter implements the expression language used by --where to
+// select log entries by their fields.
+//
+// Expressions compare fields with literals and combine the results:
+//
+//	subsystem == "secretstore" && status >= 500
+//	caller =~ "tenant.go" || !has(error)
+//
+// Nested values are addressed with dotted keys such as http.status.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expr is a compiled filter expression
+type Expr struct {
+	source string
+	root   node
+}
+
+// node is a single node of the expression tree
+type node interface {
+	eval(fields map[string]interface{}) bool
+}
+
+// Parse compiles a filter expression
+func Parse(expr string) (*Expr, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	return &Expr{source: expr, root: root}, nil
+}
+
+// String returns the source of the expression
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Match reports whether the decoded log entry satisfies the expression
+func (e *Expr) Match(fields map[string]interface{}) bool {
+	return e.root.eval(fields)
+}
+
+// MatchLine reports whether a JSON log line satisfies the expression.
+// Lines that are not JSON objects always match so they are never hidden.
+func (e *Expr) MatchLine(jsonLine string) bool {
+	matched, ok := e.MatchJSON(jsonLine)
+	return matched || !ok
+}
+
+// MatchJSON reports whether a JSON log line satisfies the expression, and
+// whether the line is a JSON object at all. Lines that are not never match.
+func (e *Expr) MatchJSON(jsonLine string) (matched, ok bool) {
+	var rawLog map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonLine), &rawLog); err != nil {
+		return false, false
+	}
+
+	return e.Match(rawLog), true
+}
+
+// Lookup resolves a possibly dotted key against a decoded log entry. A key
+// that exists literally (such as "http.status") takes precedence over the
+// nested path.
+func Lookup(fields map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := fields[key]; ok {
+		return value, true
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] != '.' {
+			continue
+		}
+
+		switch child := fields[key[:i]].(type) {
+		case map[string]interface{}:
+			if value, ok := Lookup(child, key[i+1:]); ok {
+				return value, true
+			}
+		case []interface{}:
+			if value, ok := lookupIndex(child, key[i+1:]); ok {
+				return value, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// lookupIndex resolves a key whose first segment is an array index
+func lookupIndex(items []interface{}, key string) (interface{}, bool) {
+	index, rest, nested := strings.Cut(key, ".")
+
+	n, err := strconv.Atoi(index)
+	if err != nil || n < 0 || n >= len(items) {
+		return nil, false
+	}
+
+	if !nested {
+		return items[n], true
+	}
+
+	switch child := items[n].(type) {
+	case map[string]interface{}:
+		return Lookup(child, rest)
+	case []interface{}:
+		return lookupIndex(child, rest)
+	default:
+		return nil, false
+	}
+}
+
+// parser builds an expression tree from tokens using recursive descent
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, unexpected(tok, what)
+	}
+
+	return tok, nil
+}
+
+// parseOr parses: and ("||" and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.next()
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = orNode{left, right}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses: unary ("&&" unary)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenAnd {
+		p.next()
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = andNode{left, right}
+	}
+
+	return left, nil
+}
+
+// parseUnary parses: "!" unary | primary
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokenNot {
+		p.next()
+
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+// parsePrimary parses: "(" expr ")" | "has" "(" field ")" | field op literal
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.expect(tokenRParen, `")"`); err != nil {
+			return nil, err
+		}
+
+		return inner, nil
+	case tokenIdent:
+		if tok.text == "has" && p.peek().kind == tokenLParen {
+			return p.parseHas()
+		}
+
+		return p.parseComparison(tok.text)
+	default:
+		return nil, unexpected(tok, "field name")
+	}
+}
+
+// parseHas parses the argument list of has(field)
+func (p *parser) parseHas() (node, error) {
+	p.next()
+
+	field := p.next()
+	if field.kind != tokenIdent && field.kind != tokenString {
+		return nil, unexpected(field, "field name")
+	}
+
+	if _, err := p.expect(tokenRParen, `")"`); err != nil {
+		return nil, err
+	}
+
+	return hasNode{field: field.text}, nil
+}
+
+// parseComparison parses the operator and literal following a field name
+func (p *parser) parseComparison(field string) (node, error) {
+	opTok, err := p.expect(tokenOp, "comparison operator")
+	if err != nil {
+		return nil, err
+	}
+
+	litTok := p.next()
+
+	cmp := compareNode{field: field, op: opTok.text}
+
+	switch litTok.kind {
+	case tokenString:
+		cmp.literal = litTok.text
+	case tokenNumber:
+		n, _ := strconv.ParseFloat(litTok.text, 64)
+		cmp.literal = n
+	case tokenIdent:
+		switch litTok.text {
+		case "true":
+			cmp.literal = true
+		case "false":
+			cmp.literal = false
+		case "null":
+			cmp.literal = nil
+		default:
+			return nil, unexpected(litTok, "literal value")
+		}
+	default:
+		return nil, unexpected(litTok, "literal value")
+	}
+
+	if cmp.op == "=~" || cmp.op == "!~" {
+		pattern, ok := cmp.literal.(string)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs a string pattern at position %d", cmp.op, litTok.pos)
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		cmp.pattern = re
+	}
+
+	return cmp, nil
+}
+
+// unexpected builds a parse error for a token that does not fit the grammar
+func unexpected(tok token, want string) error {
+	if tok.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of expression, expected %s", want)
+	}
+
+	return fmt.Errorf("unexpected %q at position %d, expected %s", tok.text, tok.pos, want)
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(fields map[string]interface{}) bool {
+	return n.left.eval(fields) && n.right.eval(fields)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(fields map[string]interface{}) bool {
+	return n.left.eval(fields) || n.right.eval(fields)
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(fields map[string]interface{}) bool {
+	return !n.operand.eval(fields)
+}
+
+type hasNode struct{ field string }
+
+func (n hasNode) eval(fields map[string]interface{}) bool {
+	_, ok := Lookup(fields, n.field)
+	return ok
+}
+
+// compareNode compares a field with a literal. Missing fields behave like
+// null, so only "!=" and "!~" match them.
+type compareNode struct {
+	field   string
+	op      string
+	literal interface{}
+	pattern *regexp.Regexp
+}
+
+func (n compareNode) eval(fields map[string]interface{}) bool {
+	value, _ := Lookup(fields, n.field)
+
+	switch n.op {
+	case "==":
+		return equal(value, n.literal)
+	case "!=":
+		return !equal(value, n.literal)
+	case "=~":
+		return value != nil && n.pattern.MatchString(stringify(value))
+	case "!~":
+		return value == nil || !n.pattern.MatchString(stringify(value))
+	}
+
+	cmp, ok := compare(value, n.literal)
+	if !ok {
+		return false
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	default:
+		return false
+	}
+}
+
+// equal compares a field value with a literal, treating numeric strings as
+// numbers when the literal is a number
+func equal(value, literal interface{}) bool {
+	switch lit := literal.(type) {
+	case nil:
+		return value == nil
+	case float64:
+		n, ok := toNumber(value)
+		return ok && n == lit
+	case bool:
+		b, ok := value.(bool)
+		return ok && b == lit
+	case string:
+		return value != nil && stringify(value) == lit
+	default:
+		return false
+	}
+}
+
+// compare orders a field value against a literal, numerically when both
+// are numbers and lexically when both are strings
+func compare(value, literal interface{}) (int, bool) {
+	if lit, ok := literal.(float64); ok {
+		n, ok := toNumber(value)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case n < lit:
+			return -1, true
+		case n > lit:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	lit, ok := literal.(string)
+	if !ok {
+		return 0, false
+	}
+
+	if n, isNum := toNumber(value); isNum {
+		if l, err := strconv.ParseFloat(lit, 64); err == nil {
+			return compare(n, l)
+		}
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+
+	return strings.Compare(s, lit), true
+}
+
+// toNumber converts JSON numbers and numeric strings to float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// stringify renders a field value for string comparison and matching
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
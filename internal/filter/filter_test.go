@@ -0,0 +1,139 @@
+package filter
+
+import (
+	"testing"
+)
+
+const sampleLine = `{"level":"error","subsystem":"secretstore","status":503,"latency":"120",` +
+	`"caller":"internal/secrets/tenant.go:125","http":{"method":"GET","status":500},` +
+	`"tags":["a","b"],"retry":true,"error":null,"http.route":"/api"}`
+
+func TestMatchLine(t *testing.T) {
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{`subsystem == "secretstore"`, true},
+		{`subsystem != "secretstore"`, false},
+		{`status >= 500`, true},
+		{`status < 500`, false},
+		{`status == 503`, true},
+		{`status == "503"`, true},
+		{`latency > 100`, true},
+		{`latency <= 100`, false},
+		{`subsystem == "secretstore" && status >= 500`, true},
+		{`subsystem == "other" || status >= 500`, true},
+		{`subsystem == "other" || status < 500`, false},
+		{`!(subsystem == "other")`, true},
+		{`caller =~ "tenant.go"`, true},
+		{`caller =~ "tenant\.go:\d+"`, true},
+		{`caller !~ "tenant"`, false},
+		{`http.status == 500`, true},
+		{`http.method == 'GET'`, true},
+		{`http.route == "/api"`, true},
+		{`tags.1 == "b"`, true},
+		{`retry == true`, true},
+		{`error == null`, true},
+		{`has(error)`, true},
+		{`has(http.status)`, true},
+		{`has(missing)`, false},
+		{`!has(missing)`, true},
+		{`missing == "x"`, false},
+		{`missing != "x"`, true},
+		{`missing > 1`, false},
+		{`missing == null`, true},
+		{`level == "error" && (status == 404 || http.status == 500)`, true},
+		{`level >= "a"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.expr, err)
+			}
+
+			if result := expr.MatchLine(sampleLine); result != tt.match {
+				t.Errorf("Parse(%q).MatchLine() = %v, want %v", tt.expr, result, tt.match)
+			}
+		})
+	}
+}
+
+func TestMatchLineInvalidJSON(t *testing.T) {
+	expr, err := Parse(`status >= 500`)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	if !expr.MatchLine("plain text line") {
+		t.Error("MatchLine() should match lines that are not JSON")
+	}
+
+	if matched, ok := expr.MatchJSON("plain text line"); matched || ok {
+		t.Errorf("MatchJSON() = %v, %v, want false, false", matched, ok)
+	}
+
+	if matched, ok := expr.MatchJSON(`{"status":503}`); !matched || !ok {
+		t.Errorf("MatchJSON() = %v, %v, want true, true", matched, ok)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`status`,
+		`status >=`,
+		`status = 500`,
+		`status >= 500 &&`,
+		`(status >= 500`,
+		`subsystem == "unterminated`,
+		`caller =~ 5`,
+		`caller =~ "("`,
+		`status >= 500 extra`,
+		`has(`,
+		`== 5`,
+		`status == bogus`,
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			if _, err := Parse(input); err == nil {
+				t.Errorf("Parse(%q) expected error but got none", input)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	fields := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": map[string]interface{}{"c": "deep"},
+		},
+		"a.b": "literal",
+		"list": []interface{}{
+			map[string]interface{}{"name": "first"},
+		},
+	}
+
+	tests := []struct {
+		key      string
+		expected interface{}
+		found    bool
+	}{
+		{"a.b", "literal", true},
+		{"a.b.c", "deep", true},
+		{"list.0.name", "first", true},
+		{"list.1.name", nil, false},
+		{"a.x", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			value, found := Lookup(fields, tt.key)
+			if found != tt.found || value != tt.expected {
+				t.Errorf("Lookup(%q) = (%v, %v), want (%v, %v)", tt.key, value, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
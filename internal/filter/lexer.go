@@ -0,0 +1,162 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the type of a lexical token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+// token is a single lexical token of a filter expression
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// comparisonOps lists the comparison operators, longest first so that
+// "<=" is matched before "<"
+var comparisonOps = []string{"==", "!=", "<=", ">=", "=~", "!~", "<", ">"}
+
+// tokenize splits a filter expression into tokens
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+
+	i := 0
+	for i < len(input) {
+		c := rune(input[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case strings.HasPrefix(input[i:], "&&"):
+			tokens = append(tokens, token{kind: tokenAnd, text: "&&", pos: i})
+			i += 2
+		case strings.HasPrefix(input[i:], "||"):
+			tokens = append(tokens, token{kind: tokenOr, text: "||", pos: i})
+			i += 2
+		case c == '"' || c == '\'':
+			text, end, err := scanString(input, i)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = end
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			end := i + 1
+			for end < len(input) && isNumberChar(rune(input[end])) {
+				end++
+			}
+
+			if _, err := strconv.ParseFloat(input[i:end], 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", input[i:end], i)
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: input[i:end], pos: i})
+			i = end
+		case isIdentStart(c):
+			end := i + 1
+			for end < len(input) && isIdentChar(rune(input[end])) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: input[i:end], pos: i})
+			i = end
+		default:
+			op := matchOp(input[i:])
+			if op == "" {
+				if c == '!' {
+					tokens = append(tokens, token{kind: tokenNot, text: "!", pos: i})
+					i++
+
+					continue
+				}
+
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(input)})
+
+	return tokens, nil
+}
+
+// scanString reads a quoted string starting at start, returning its
+// unescaped contents and the index just past the closing quote
+func scanString(input string, start int) (string, int, error) {
+	quote := input[start]
+
+	var sb strings.Builder
+
+	for i := start + 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			// Only quotes and backslashes are escaped so that regular
+			// expressions such as "\d+" can be written without doubling
+			if i+1 < len(input) && (input[i+1] == quote || input[i+1] == '\\') {
+				i++
+			}
+
+			sb.WriteByte(input[i])
+		case quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(input[i])
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string starting at position %d", start)
+}
+
+// matchOp returns the comparison operator at the start of input, if any
+func matchOp(input string) string {
+	for _, op := range comparisonOps {
+		if strings.HasPrefix(input, op) {
+			return op
+		}
+	}
+
+	return ""
+}
+
+// isIdentStart reports whether c can start a field name
+func isIdentStart(c rune) bool {
+	return unicode.IsLetter(c) || c == '_' || c == '@' || c == '$'
+}
+
+// isIdentChar reports whether c can appear in a field name
+func isIdentChar(c rune) bool {
+	return isIdentStart(c) || unicode.IsDigit(c) || c == '.' || c == '-'
+}
+
+// isNumberChar reports whether c can appear in a number literal
+func isNumberChar(c rune) bool {
+	return unicode.IsDigit(c) || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-'
+}
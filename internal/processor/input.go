@@ -0,0 +1,253 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// stdinName is the file argument that refers to standard input
+const stdinName = "-"
+
+// Line is a single line of input together with the name of its source
+type Line struct {
+	Source string
+	Text   string
+}
+
+// LineReader yields input lines one at a time
+type LineReader interface {
+	// ReadLine returns the next line, or io.EOF once the input is exhausted
+	ReadLine() (Line, error)
+	// Close releases any files held by the reader
+	Close() error
+}
+
+// OpenInput opens the named files for reading, or stdin when none are
+// given. Files are read one after another unless follow is set, in which
+// case they are tailed concurrently until ctx is cancelled.
+func OpenInput(ctx context.Context, stdin io.Reader, paths []string, follow bool) (LineReader, error) {
+	if len(paths) == 0 {
+		return NewScannerReader(stdinName, stdin), nil
+	}
+
+	if follow {
+		return openFollowers(ctx, stdin, paths)
+	}
+
+	readers, err := openFiles(stdin, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sequentialReader{readers: readers}, nil
+}
+
+// openFiles opens a LineReader for each path, treating "-" as stdin
+func openFiles(stdin io.Reader, paths []string) ([]LineReader, error) {
+	readers := make([]LineReader, 0, len(paths))
+
+	for _, path := range paths {
+		if path == stdinName {
+			readers = append(readers, NewScannerReader(stdinName, stdin))
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			closeAll(readers)
+			return nil, fmt.Errorf("failed to open %s: %v", path, err)
+		}
+
+		readers = append(readers, &fileReader{LineReader: NewScannerReader(path, file), file: file})
+	}
+
+	return readers, nil
+}
+
+// scannerReader reads lines from an io.Reader
+type scannerReader struct {
+	name    string
+	scanner *bufio.Scanner
+}
+
+// NewScannerReader creates a LineReader that reads lines from r
+func NewScannerReader(name string, r io.Reader) LineReader {
+	return &scannerReader{name: name, scanner: bufio.NewScanner(r)}
+}
+
+func (sr *scannerReader) ReadLine() (Line, error) {
+	if sr.scanner.Scan() {
+		return Line{Source: sr.name, Text: sr.scanner.Text()}, nil
+	}
+
+	if err := sr.scanner.Err(); err != nil {
+		return Line{}, err
+	}
+
+	return Line{}, io.EOF
+}
+
+func (sr *scannerReader) Close() error {
+	return nil
+}
+
+// fileReader closes the underlying file along with its LineReader
+type fileReader struct {
+	LineReader
+	file *os.File
+}
+
+func (fr *fileReader) Close() error {
+	return fr.file.Close()
+}
+
+// sequentialReader reads each of its readers to the end in turn
+type sequentialReader struct {
+	readers []LineReader
+}
+
+func (sr *sequentialReader) ReadLine() (Line, error) {
+	for len(sr.readers) > 0 {
+		line, err := sr.readers[0].ReadLine()
+		if err != io.EOF {
+			return line, err
+		}
+
+		_ = sr.readers[0].Close()
+		sr.readers = sr.readers[1:]
+	}
+
+	return Line{}, io.EOF
+}
+
+func (sr *sequentialReader) Close() error {
+	closeAll(sr.readers)
+	sr.readers = nil
+
+	return nil
+}
+
+// closeAll closes every reader, ignoring errors
+func closeAll(readers []LineReader) {
+	for _, r := range readers {
+		_ = r.Close()
+	}
+}
+
+// channelReader collects lines produced concurrently by several readers
+type channelReader struct {
+	lines  chan Line
+	errs   chan error
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newChannelReader starts a goroutine for each reader that forwards its
+// lines, interleaving them in the order they arrive. Cancelling ctx stops
+// the goroutines once their readers return.
+func newChannelReader(ctx context.Context, cancel context.CancelFunc, readers []LineReader) *channelReader {
+	cr := &channelReader{
+		lines:  make(chan Line),
+		errs:   make(chan error, len(readers)),
+		cancel: cancel,
+	}
+
+	for _, r := range readers {
+		cr.wg.Add(1)
+
+		go func(r LineReader) {
+			defer cr.wg.Done()
+			defer func() { _ = r.Close() }()
+
+			for {
+				line, err := r.ReadLine()
+				if err != nil {
+					if err != io.EOF {
+						cr.errs <- err
+					}
+
+					return
+				}
+
+				select {
+				case cr.lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(r)
+	}
+
+	go func() {
+		cr.wg.Wait()
+		close(cr.lines)
+	}()
+
+	return cr
+}
+
+func (cr *channelReader) ReadLine() (Line, error) {
+	select {
+	case err := <-cr.errs:
+		return Line{}, err
+	case line, ok := <-cr.lines:
+		if !ok {
+			return Line{}, io.EOF
+		}
+
+		return line, nil
+	}
+}
+
+func (cr *channelReader) Close() error {
+	cr.cancel()
+	return nil
+}
+
+// readResult is the outcome of a single ReadLine call
+type readResult struct {
+	line Line
+	err  error
+}
+
+// contextReader reads from a LineReader in the background so that waiting
+// for input, such as a quiet stdin, can be interrupted by cancelling ctx
+type contextReader struct {
+	ctx     context.Context
+	r       LineReader
+	results chan readResult
+	pending bool
+}
+
+// withContext wraps r so that ReadLine returns ctx.Err() as soon as ctx is
+// cancelled, even while the underlying reader is blocked
+func withContext(ctx context.Context, r LineReader) LineReader {
+	return &contextReader{ctx: ctx, r: r, results: make(chan readResult, 1)}
+}
+
+func (cr *contextReader) ReadLine() (Line, error) {
+	if !cr.pending {
+		cr.pending = true
+
+		go func() {
+			line, err := cr.r.ReadLine()
+			cr.results <- readResult{line: line, err: err}
+		}()
+	}
+
+	select {
+	case res := <-cr.results:
+		cr.pending = false
+		return res.line, res.err
+	case <-cr.ctx.Done():
+		return Line{}, cr.ctx.Err()
+	}
+}
+
+func (cr *contextReader) Close() error {
+	return cr.r.Close()
+}
@@ -0,0 +1,217 @@
+package processor
+
+import (
+	"container/heap"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dougalmatthews/glug/logparser"
+)
+
+// TimeFunc extracts the timestamp of a log line
+type TimeFunc func(line string) (time.Time, bool)
+
+// OpenMerged opens the named files and reads them as a single stream
+// ordered by each entry's timestamp. Lines without a timestamp keep the
+// time of the line before them, so they stay next to the entry they belong to.
+func OpenMerged(stdin io.Reader, paths []string, timeOf TimeFunc) (LineReader, error) {
+	readers, err := openFiles(stdin, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMergeReader(readers, timeOf), nil
+}
+
+// mergeHead is the next unread line of one merged source
+type mergeHead struct {
+	line  Line
+	time  time.Time
+	index int
+}
+
+// mergeHeap orders heads by time, then by source index so that ties are
+// resolved the same way every run
+type mergeHeap []mergeHead
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if !h[i].time.Equal(h[j].time) {
+		return h[i].time.Before(h[j].time)
+	}
+
+	return h[i].index < h[j].index
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeHead)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return head
+}
+
+// mergeReader performs a k-way merge of its readers by timestamp
+type mergeReader struct {
+	readers  []LineReader
+	lastTime []time.Time
+	timeOf   TimeFunc
+	heads    mergeHeap
+	started  bool
+}
+
+// newMergeReader creates a LineReader merging readers by timestamp
+func newMergeReader(readers []LineReader, timeOf TimeFunc) *mergeReader {
+	return &mergeReader{
+		readers:  readers,
+		lastTime: make([]time.Time, len(readers)),
+		timeOf:   timeOf,
+	}
+}
+
+// advance reads the next line of reader i onto the heap
+func (mr *mergeReader) advance(i int) error {
+	line, err := mr.readers[i].ReadLine()
+	if err == io.EOF {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if t, ok := mr.timeOf(line.Text); ok {
+		mr.lastTime[i] = t
+	}
+
+	heap.Push(&mr.heads, mergeHead{line: line, time: mr.lastTime[i], index: i})
+
+	return nil
+}
+
+func (mr *mergeReader) ReadLine() (Line, error) {
+	if !mr.started {
+		mr.started = true
+
+		for i := range mr.readers {
+			if err := mr.advance(i); err != nil {
+				return Line{}, err
+			}
+		}
+	}
+
+	if mr.heads.Len() == 0 {
+		return Line{}, io.EOF
+	}
+
+	head := heap.Pop(&mr.heads).(mergeHead)
+
+	if err := mr.advance(head.index); err != nil {
+		return Line{}, err
+	}
+
+	return head.line, nil
+}
+
+func (mr *mergeReader) Close() error {
+	closeAll(mr.readers)
+	return nil
+}
+
+// SourceTagger prefixes lines with a colored tag naming their source file
+type SourceTagger struct {
+	tags  map[string]string
+	width int
+}
+
+// NewSourceTagger creates a tagger for the given sources, padding the tags
+// to a common width so that the log lines after them stay aligned. The tags
+// are colored from the palette of theme when colored is set.
+func NewSourceTagger(sources []string, theme *logparser.Theme, colored bool) *SourceTagger {
+	if len(sources) == 0 {
+		return &SourceTagger{}
+	}
+
+	names := sourceNames(sources)
+
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+
+	tags := make(map[string]string, len(sources))
+
+	for i, source := range sources {
+		name := names[i]
+		padding := strings.Repeat(" ", width-len(name))
+
+		tag := "[" + name + "]"
+		if colored {
+			tag = theme.PaletteStyle(i).Sprint(tag)
+		}
+
+		tags[source] = tag + padding
+	}
+
+	// The tags are followed by a space
+	return &SourceTagger{tags: tags, width: width + len("[] ")}
+}
+
+// sourceNames returns the names the sources are tagged with: their base names,
+// with as many parent directories as it takes to tell apart sources that
+// share one, so that svc-a/app.log and svc-b/app.log keep their directories
+func sourceNames(sources []string) []string {
+	parts := make([][]string, len(sources))
+	depths := make([]int, len(sources))
+	names := make([]string, len(sources))
+
+	for i, source := range sources {
+		parts[i] = strings.Split(filepath.ToSlash(filepath.Clean(source)), "/")
+		depths[i] = 1
+	}
+
+	for {
+		counts := make(map[string]int, len(sources))
+
+		for i := range sources {
+			names[i] = strings.Join(parts[i][len(parts[i])-depths[i]:], "/")
+			counts[names[i]]++
+		}
+
+		grown := false
+
+		for i := range sources {
+			if counts[names[i]] > 1 && depths[i] < len(parts[i]) {
+				depths[i]++
+				grown = true
+			}
+		}
+
+		if !grown {
+			return names
+		}
+	}
+}
+
+// Width returns the number of columns the tag in front of each line takes up,
+// which is 0 when there are no sources to tag
+func (st *SourceTagger) Width() int {
+	return st.width
+}
+
+// Tag prefixes a formatted line with the tag for its source
+func (st *SourceTagger) Tag(source, formatted string) string {
+	tag, ok := st.tags[source]
+	if !ok {
+		return formatted
+	}
+
+	return tag + " " + formatted
+}
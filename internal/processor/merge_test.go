@@ -0,0 +1,137 @@
+package processor
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dougalmatthews/glug/logparser"
+)
+
+func TestOpenMergedOrdersByTimestamp(t *testing.T) {
+	dir := t.TempDir()
+	api := filepath.Join(dir, "api.log")
+	worker := filepath.Join(dir, "worker.log")
+
+	appendToFile(t, api, `{"time":1609459200,"msg":"api 1"}`+"\n"+
+		`{"time":1609459260,"msg":"api 2"}`+"\n"+
+		"stack trace continuation\n")
+	appendToFile(t, worker, `{"time":"2021-01-01T00:00:30Z","msg":"worker 1"}`+"\n"+
+		`{"time":1609459260000,"msg":"worker 2"}`+"\n"+
+		`{"time":1609459300,"msg":"worker 3"}`+"\n")
+
+	formatter := logparser.NewFormatter(logparser.Options{})
+
+	input, err := OpenMerged(nil, []string{api, worker}, formatter.EntryTime)
+	if err != nil {
+		t.Fatalf("OpenMerged() error: %v", err)
+	}
+	defer func() { _ = input.Close() }()
+
+	var got []string
+
+	for {
+		line, err := input.ReadLine()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("ReadLine() error: %v", err)
+		}
+
+		got = append(got, filepath.Base(line.Source)+": "+line.Text)
+	}
+
+	want := []string{
+		`api.log: {"time":1609459200,"msg":"api 1"}`,
+		`worker.log: {"time":"2021-01-01T00:00:30Z","msg":"worker 1"}`,
+		`api.log: {"time":1609459260,"msg":"api 2"}`,
+		"api.log: stack trace continuation",
+		`worker.log: {"time":1609459260000,"msg":"worker 2"}`,
+		`worker.log: {"time":1609459300,"msg":"worker 3"}`,
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("merged order:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMergeReaderEmptySources(t *testing.T) {
+	timeOf := func(string) (time.Time, bool) { return time.Time{}, false }
+
+	mr := newMergeReader([]LineReader{
+		NewScannerReader("empty", strings.NewReader("")),
+		NewScannerReader("one", strings.NewReader("only line\n")),
+	}, timeOf)
+
+	line, err := mr.ReadLine()
+	if err != nil || line.Text != "only line" {
+		t.Fatalf("ReadLine() = (%v, %v), want only line", line, err)
+	}
+
+	if _, err := mr.ReadLine(); err != io.EOF {
+		t.Errorf("ReadLine() error = %v, want io.EOF", err)
+	}
+}
+
+func TestSourceTaggerAlignsTags(t *testing.T) {
+	tagger := NewSourceTagger([]string{"/var/log/a.log", "logs/worker.log"}, nil, false)
+
+	first := tagger.Tag("/var/log/a.log", "line")
+	second := tagger.Tag("logs/worker.log", "line")
+
+	if !strings.Contains(first, "[a.log]") || !strings.Contains(second, "[worker.log]") {
+		t.Errorf("Tag() missing source names: %q, %q", first, second)
+	}
+
+	if len(first) != len(second) {
+		t.Errorf("Tag() results not aligned: %q, %q", first, second)
+	}
+
+	if got := tagger.Tag("unknown", "line"); got != "line" {
+		t.Errorf("Tag() for unknown source = %q, want %q", got, "line")
+	}
+
+	if want := len(second) - len("line"); tagger.Width() != want {
+		t.Errorf("Width() = %d, want %d", tagger.Width(), want)
+	}
+
+	theme, err := logparser.BuiltinTheme("high-contrast")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	colored := NewSourceTagger([]string{"a.log", "b.log"}, theme, true)
+	if got := colored.Tag("b.log", "line"); !strings.HasPrefix(got, theme.PaletteStyle(1).Sprint("[b.log]")) {
+		t.Errorf("Tag() with color = %q, want a tag in the theme's second palette color", got)
+	}
+
+	if got := NewSourceTagger(nil, theme, true).Width(); got != 0 {
+		t.Errorf("Width() without sources = %d, want 0", got)
+	}
+}
+
+func TestSourceTaggerCollidingNames(t *testing.T) {
+	tests := []struct {
+		sources []string
+		want    []string
+	}{
+		{[]string{"svc-a/app.log", "svc-b/app.log"}, []string{"svc-a/app.log", "svc-b/app.log"}},
+		{[]string{"/x/a/app.log", "/y/a/app.log", "db.log"}, []string{"x/a/app.log", "y/a/app.log", "db.log"}},
+		{[]string{"app.log", "logs/app.log"}, []string{"app.log", "logs/app.log"}},
+		{[]string{"a.log", "/var/log/worker.log"}, []string{"a.log", "worker.log"}},
+	}
+
+	for _, tt := range tests {
+		tagger := NewSourceTagger(tt.sources, nil, false)
+
+		for i, source := range tt.sources {
+			if got := tagger.Tag(source, "line"); !strings.HasPrefix(got, "["+tt.want[i]+"]") {
+				t.Errorf("Tag(%q) = %q, want the tag [%s]", source, got, tt.want[i])
+			}
+		}
+	}
+}
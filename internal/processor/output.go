@@ -1,46 +1,133 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
-// OutputHandler manages output to either stdout or pager
+// ErrPagerClosed is returned by AddLine once the user has quit the pager
+var ErrPagerClosed = errors.New("pager closed")
+
+// OutputHandler manages output to either stdout or a pager. The pager is
+// started up-front and fed through a pipe as lines are produced, so output
+// appears immediately and nothing is held in memory.
 type OutputHandler struct {
-	usePager    bool
-	outputLines []string
+	usePager bool
+	pagerCmd string
+	out      io.Writer
+	pipe     io.WriteCloser
+	done     chan struct{}
+	waitErr  error
 }
 
-// NewOutputHandler creates a new output handler
-func NewOutputHandler(usePager bool) *OutputHandler {
+// NewOutputHandler creates a new output handler writing to out. pagerCmd
+// is a full command line for the pager; when empty the pager is taken from
+// $GLUG_PAGER or $PAGER, falling back to the best pager found on the PATH.
+func NewOutputHandler(usePager bool, pagerCmd string, out io.Writer) *OutputHandler {
 	return &OutputHandler{
-		usePager:    usePager,
-		outputLines: make([]string, 0),
+		usePager: usePager,
+		pagerCmd: pagerCmd,
+		out:      out,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the pager if one is in use
+func (oh *OutputHandler) Start() error {
+	if !oh.usePager {
+		return nil
+	}
+
+	commandLine := resolvePager(oh.pagerCmd)
+
+	args, err := splitCommandLine(commandLine)
+	if err != nil {
+		return fmt.Errorf("invalid pager command %q: %v", commandLine, err)
+	}
+
+	// Paging through cat is the same as writing directly
+	if len(args) == 0 || (len(args) == 1 && args[0] == "cat") {
+		return nil
+	}
+
+	if err := oh.startCommand(pagerCommand(args)); err != nil {
+		return fmt.Errorf("failed to start pager %s: %v", args[0], err)
+	}
+
+	return nil
+}
+
+// startCommand runs cmd as the pager, feeding it through a pipe
+func (oh *OutputHandler) startCommand(cmd *exec.Cmd) error {
+	cmd.Stdout = oh.out
+	cmd.Stderr = os.Stderr
+
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	oh.pipe = pipe
+
+	go func() {
+		oh.waitErr = cmd.Wait()
+		close(oh.done)
+	}()
+
+	return nil
+}
+
+// Done is closed when the pager exits, for example because the user quit it
+// before the input ended. It is never closed when no pager is in use.
+func (oh *OutputHandler) Done() <-chan struct{} {
+	if oh.pipe == nil {
+		return nil
 	}
+
+	return oh.done
 }
 
-// AddLine adds a line to the output buffer
-func (oh *OutputHandler) AddLine(line string) {
-	if oh.usePager {
-		oh.outputLines = append(oh.outputLines, line)
-	} else {
-		fmt.Println(line)
+// AddLine writes a line to the pager or directly to the output
+func (oh *OutputHandler) AddLine(line string) error {
+	if oh.pipe == nil {
+		_, err := fmt.Fprintln(oh.out, line)
+		return err
+	}
+
+	if _, err := io.WriteString(oh.pipe, line+"\n"); err != nil {
+		// The write end of the pipe only fails once the pager has gone away
+		return ErrPagerClosed
 	}
+
+	return nil
 }
 
-// Flush outputs all buffered lines
+// Flush signals the end of output and waits for the user to quit the pager
 func (oh *OutputHandler) Flush() error {
-	if !oh.usePager {
+	if oh.pipe == nil {
 		return nil
 	}
 
-	pagerName := detectPager()
-	content := strings.Join(oh.outputLines, "\n")
+	_ = oh.pipe.Close()
+	<-oh.done
 
-	if err := executeWithPager(content, pagerName); err != nil {
-		return fmt.Errorf("error running pager: %v", err)
+	var exitErr *exec.ExitError
+	if errors.As(oh.waitErr, &exitErr) && exitErr.ExitCode() == -1 {
+		// The pager was stopped by a signal such as Ctrl+C
+		return nil
+	}
+
+	if oh.waitErr != nil {
+		return fmt.Errorf("error running pager: %v", oh.waitErr)
 	}
 
 	return nil
@@ -61,37 +148,93 @@ func detectPager() string {
 	return "cat"
 }
 
-// executeWithPager runs the pager with the given content
-func executeWithPager(content string, pagerName string) error {
-	var cmd *exec.Cmd
-
-	// Configure pager with appropriate flags for color support
-	switch pagerName {
-	case "less":
-		// -R: enable raw control characters (colors)
-		// -X: don't clear screen on exit
-		// -F: quit if one screen
-		cmd = exec.Command("less", "-R", "-X", "-F")
-	case "more":
-		// more doesn't need special flags for colors
-		cmd = exec.Command("more")
-	case "cat":
-		// cat just outputs everything
-		cmd = exec.Command("cat")
-	default:
-		cmd = exec.Command(pagerName)
-	}
-
-	// Set up stdin for the pager
-	cmd.Stdin = strings.NewReader(content)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// resolvePager picks the pager command line: an explicit command first, then
+// $GLUG_PAGER, then $PAGER, then the best pager found on the PATH
+func resolvePager(explicit string) string {
+	candidates := []string{explicit, os.Getenv("GLUG_PAGER"), os.Getenv("PAGER")}
 
-	// Start the pager
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start pager %s: %v", pagerName, err)
+	for _, candidate := range candidates {
+		if strings.TrimSpace(candidate) != "" {
+			return candidate
+		}
+	}
+
+	return detectPager()
+}
+
+// pagerCommand builds the command for a pager. less is given flags for
+// color support through $LESS, unless the user has set their own.
+func pagerCommand(args []string) *exec.Cmd {
+	cmd := exec.Command(args[0], args[1:]...)
+
+	if filepath.Base(args[0]) == "less" && os.Getenv("LESS") == "" {
+		// R: enable raw control characters (colors)
+		// X: don't clear screen on exit
+		// F: quit if one screen
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+
+	return cmd
+}
+
+// splitCommandLine splits a command line into arguments the way a POSIX
+// shell would, honoring single quotes, double quotes and backslashes
+func splitCommandLine(commandLine string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, c := range commandLine {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '\\':
+			// Backslash escapes the next character, also inside double quotes
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+
+	if inArg {
+		args = append(args, current.String())
 	}
 
-	// Wait for the pager to complete
-	return cmd.Wait()
+	return args, nil
 }
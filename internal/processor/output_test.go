@@ -0,0 +1,194 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestDetectPager(t *testing.T) {
+	// Test that detectPager returns a valid pager
+	pager := detectPager()
+	if pager == "" {
+		t.Error("detectPager() should return a non-empty string")
+	}
+
+	// Should be one of the expected pagers
+	expectedPagers := []string{"less", "more", "cat"}
+	validPager := false
+
+	for _, expected := range expectedPagers {
+		if pager == expected {
+			validPager = true
+			break
+		}
+	}
+
+	if !validPager {
+		t.Errorf("detectPager() returned unexpected pager: %s", pager)
+	}
+}
+
+func TestOutputHandlerDirect(t *testing.T) {
+	var out bytes.Buffer
+
+	oh := NewOutputHandler(false, "", &out)
+	if err := oh.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+
+	for _, line := range []string{"line 1", "line 2"} {
+		if err := oh.AddLine(line); err != nil {
+			t.Fatalf("AddLine() error: %v", err)
+		}
+	}
+
+	if err := oh.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	if got, want := out.String(), "line 1\nline 2\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestOutputHandlerStreamsToPager(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not available")
+	}
+
+	var out bytes.Buffer
+
+	oh := &OutputHandler{usePager: true, out: &out, done: make(chan struct{})}
+	if err := oh.startCommand(exec.Command("cat")); err != nil {
+		t.Fatalf("startCommand() error: %v", err)
+	}
+
+	for _, line := range []string{"test line 1", "test line 2"} {
+		if err := oh.AddLine(line); err != nil {
+			t.Fatalf("AddLine() error: %v", err)
+		}
+	}
+
+	if err := oh.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
+	if got, want := out.String(), "test line 1\ntest line 2\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestOutputHandlerPagerQuit(t *testing.T) {
+	if _, err := exec.LookPath("true"); err != nil {
+		t.Skip("true not available")
+	}
+
+	var out bytes.Buffer
+
+	// A pager that exits immediately behaves like the user pressing q
+	oh := &OutputHandler{usePager: true, out: &out, done: make(chan struct{})}
+	if err := oh.startCommand(exec.Command("true")); err != nil {
+		t.Fatalf("startCommand() error: %v", err)
+	}
+
+	<-oh.Done()
+
+	var err error
+	for i := 0; i < 1000 && err == nil; i++ {
+		err = oh.AddLine("line after quit")
+	}
+
+	if !errors.Is(err, ErrPagerClosed) {
+		t.Errorf("AddLine() error = %v, want ErrPagerClosed", err)
+	}
+
+	if err := oh.Flush(); err != nil {
+		t.Errorf("Flush() error: %v", err)
+	}
+}
+
+func TestResolvePager(t *testing.T) {
+	tests := []struct {
+		name      string
+		explicit  string
+		glugPager string
+		pager     string
+		expected  string
+	}{
+		{"explicit wins", "bat --paging=always", "moar", "less", "bat --paging=always"},
+		{"GLUG_PAGER before PAGER", "", "moar", "less -S", "moar"},
+		{"PAGER", "", "", "less -S", "less -S"},
+		{"blank values ignored", " ", "", "more", "more"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GLUG_PAGER", tt.glugPager)
+			t.Setenv("PAGER", tt.pager)
+
+			if got := resolvePager(tt.explicit); got != tt.expected {
+				t.Errorf("resolvePager(%q) = %q, want %q", tt.explicit, got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("falls back to detection", func(t *testing.T) {
+		t.Setenv("GLUG_PAGER", "")
+		t.Setenv("PAGER", "")
+
+		if got := resolvePager(""); got != detectPager() {
+			t.Errorf("resolvePager(\"\") = %q, want %q", got, detectPager())
+		}
+	})
+}
+
+func TestPagerCommandLessEnv(t *testing.T) {
+	t.Setenv("LESS", "")
+
+	cmd := pagerCommand([]string{"/usr/bin/less"})
+	if !slices.Contains(cmd.Env, "LESS=FRX") {
+		t.Errorf("pagerCommand(less) should set LESS=FRX when $LESS is unset")
+	}
+
+	t.Setenv("LESS", "-S")
+
+	cmd = pagerCommand([]string{"less"})
+	if cmd.Env != nil {
+		t.Errorf("pagerCommand(less) should respect the user's $LESS, got env %v", cmd.Env)
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		wantErr  bool
+	}{
+		{"less", []string{"less"}, false},
+		{"  less  -R -S ", []string{"less", "-R", "-S"}, false},
+		{`bat --style="plain,numbers" --paging=always`, []string{"bat", "--style=plain,numbers", "--paging=always"}, false},
+		{`moar '--colors 16M'`, []string{"moar", "--colors 16M"}, false},
+		{`pager a\ b "quote\"inside" ''`, []string{"pager", "a b", `quote"inside`, ""}, false},
+		{`'it''s'`, []string{"its"}, false},
+		{"", nil, false},
+		{`less "unterminated`, nil, true},
+		{`less trailing\`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := splitCommandLine(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitCommandLine(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("splitCommandLine(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
@@ -1,99 +1,306 @@
 package processor
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"text/template"
+	"time"
 
+	"github.com/dougalmatthews/glug/internal/filter"
 	"github.com/dougalmatthews/glug/logparser"
 )
 
 // LogProcessor handles the processing of log input
 type LogProcessor struct {
-	config       *Config
-	customColors map[string]string
-	output       *OutputHandler
+	config    *Config
+	formatter *logparser.Formatter
+	profiles  []profileRules
+	tagger    *SourceTagger
+	// shownEntry records whether the last JSON entry was shown, so that the
+	// lines that are not JSON after it, such as stack traces, go with it
+	shownEntry bool
+}
+
+// profileRules is a profile ready to be applied to matching lines
+type profileRules struct {
+	match     *filter.Expr
+	config    *Config
+	formatter *logparser.Formatter
 }
 
 // Config represents the application configuration
 type Config struct {
 	MinLevel           string
 	UsePager           bool
+	PagerCmd           string
+	Highlights         []logparser.HighlightRule
+	FieldColors        []logparser.FieldColorRule
+	HashFields         []string
 	ConvertTimestamps  bool
 	TimestampFieldList []string
+	AutoTimestamps     bool
+	TimestampExclude   []string
+	Fields             logparser.FieldMapping
+	LevelScheme        logparser.LevelScheme
+	Where              *filter.Expr
+	FlattenDepth       int
+	Expand             bool
+	ExpandFields       []string
+	ModulePaths        []string
+	Output             logparser.OutputFormat
+	Columns            []string
+	Template           *template.Template
+	ShowFields         []string
+	HideFields         []string
+	FieldsFirst        bool
+	Width              int
+	Wrap               bool
+	ColumnWidth        int
+	Theme              *logparser.Theme
+	Color              logparser.ColorMode
+	TimeFormat         logparser.TimeFormat
+	Location           *time.Location
+	TimeLayouts        []string
+	Files              []string
+	Follow             bool
+	Merge              bool
+	SourceTags         bool
+	Profiles           []Profile
+}
+
+// Profile holds settings applied to the lines that match it instead of the
+// top-level settings. Only the formatting and filtering settings of Config
+// (level, colors, theme, timestamps, fields, level scheme, where,
+// flattening, expansion, module paths, output format, field selection and
+// table layout) are used.
+type Profile struct {
+	Name   string
+	Match  *filter.Expr
+	Config *Config
 }
 
 // NewLogProcessor creates a new log processor
-func NewLogProcessor(config *Config, customColors map[string]string) *LogProcessor {
+func NewLogProcessor(config *Config) *LogProcessor {
+	// Source tags would break the structured output formats
+	var tagger *SourceTagger
+	if config.SourceTags && config.Output.IsText() {
+		tagger = NewSourceTagger(config.Files, config.Theme, config.Color.Enabled())
+	}
+
+	// Tables are fitted to the room left after the source tags
+	reserved := 0
+	if tagger != nil {
+		reserved = tagger.Width()
+	}
+
+	// Deltas are measured from the previous entry whichever profile it used
+	previous := &logparser.PreviousTime{}
+
+	profiles := make([]profileRules, len(config.Profiles))
+	for i, profile := range config.Profiles {
+		profiles[i] = profileRules{
+			match:     profile.Match,
+			config:    profile.Config,
+			formatter: newFormatter(profile.Config, reserved, previous),
+		}
+	}
+
 	return &LogProcessor{
-		config:       config,
-		customColors: customColors,
-		output:       NewOutputHandler(config.UsePager),
+		config:    config,
+		formatter: newFormatter(config, reserved, previous),
+		profiles:  profiles,
+		tagger:    tagger,
+	}
+}
+
+// newFormatter creates a formatter for the formatting settings in config,
+// leaving reserved columns of the terminal width for a prefix
+func newFormatter(config *Config, reserved int, previous *logparser.PreviousTime) *logparser.Formatter {
+	width := 0
+	if config.Width > 0 {
+		width = max(config.Width-reserved, 1)
+	}
+
+	return logparser.NewFormatter(logparser.Options{
+		Highlights:        config.Highlights,
+		FieldColors:       config.FieldColors,
+		HashFields:        config.HashFields,
+		ConvertTimestamps: config.ConvertTimestamps,
+		TimestampFields:   config.TimestampFieldList,
+		AutoTimestamps:    config.AutoTimestamps,
+		TimestampExclude:  config.TimestampExclude,
+		Fields:            config.Fields,
+		LevelScheme:       config.LevelScheme,
+		FlattenDepth:      config.FlattenDepth,
+		Expand:            config.Expand,
+		ExpandFields:      config.ExpandFields,
+		ModulePaths:       config.ModulePaths,
+		Output:            config.Output,
+		Columns:           config.Columns,
+		Template:          config.Template,
+		ShowFields:        config.ShowFields,
+		HideFields:        config.HideFields,
+		FieldsFirst:       config.FieldsFirst,
+		Width:             width,
+		Wrap:              config.Wrap,
+		ColumnWidth:       config.ColumnWidth,
+		Theme:             config.Theme,
+		Color:             config.Color,
+		TimeFormat:        config.TimeFormat,
+		Location:          config.Location,
+		TimeLayouts:       config.TimeLayouts,
+		PreviousTime:      previous,
+	})
+}
+
+// rulesFor returns the settings and formatter for a line: those of the first
+// profile matching it, or the top-level ones when no profile matches
+func (lp *LogProcessor) rulesFor(line string) (*Config, *logparser.Formatter) {
+	for _, profile := range lp.profiles {
+		if profile.match != nil && profile.match.MatchLine(line) {
+			return profile.config, profile.formatter
+		}
 	}
+
+	return lp.config, lp.formatter
 }
 
-// Process reads from stdin and processes log entries
-func (lp *LogProcessor) Process(ctx context.Context) error {
-	scanner := bufio.NewScanner(os.Stdin)
+// Process reads log entries from the configured files, or from in when no
+// files are configured, and writes the formatted entries to out
+func (lp *LogProcessor) Process(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	input, err := lp.openInput(ctx, in)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = input.Close() }()
+
+	output := NewOutputHandler(lp.config.UsePager, lp.config.PagerCmd, out)
+	if err := output.Start(); err != nil {
+		return err
+	}
+
+	// Stop reading once the user quits the pager
+	go func() {
+		select {
+		case <-output.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	input = withContext(ctx, input)
+
+	if header, ok := lp.formatter.Header(); ok {
+		if err := output.AddLine(header); err != nil {
+			if errors.Is(err, ErrPagerClosed) {
+				return output.Flush()
+			}
+
+			return fmt.Errorf("error writing output: %v", err)
+		}
+	}
 
-	for scanner.Scan() {
+	for {
 		// Check if we should exit due to signal
 		select {
 		case <-ctx.Done():
-			return nil
+			return output.Flush()
 		default:
 		}
 
-		line := scanner.Text()
-		if line == "" {
-			continue
+		next, err := input.ReadLine()
+		if err == io.EOF {
+			break
 		}
 
-		formatted, err := lp.processLine(line)
 		if err != nil {
-			// If parsing fails, just print the original line
-			lp.output.AddLine(line)
+			// Don't report error if context was cancelled (user pressed Ctrl+C
+			// or quit the pager)
+			select {
+			case <-ctx.Done():
+				return output.Flush()
+			default:
+				return fmt.Errorf("error reading input: %v", err)
+			}
+		}
+
+		formatted, show := lp.processLine(next.Text)
+		if !show {
 			continue
 		}
 
-		// Apply level filtering if specified
-		if lp.config.MinLevel != "" {
-			shouldShow, err := logparser.ShouldShowLogLevel(line, lp.config.MinLevel)
-			if err != nil {
-				// If level parsing fails, show the line (fail open)
-				lp.output.AddLine(formatted)
-				continue
-			}
+		if lp.tagger != nil {
+			formatted = lp.tagger.Tag(next.Source, formatted)
+		}
 
-			if !shouldShow {
-				continue
+		if err := output.AddLine(formatted); err != nil {
+			if errors.Is(err, ErrPagerClosed) {
+				return output.Flush()
 			}
+
+			return fmt.Errorf("error writing output: %v", err)
 		}
+	}
+
+	// Flush output
+	return output.Flush()
+}
 
-		lp.output.AddLine(formatted)
+// openInput opens the configured inputs, merging them by timestamp if requested
+func (lp *LogProcessor) openInput(ctx context.Context, stdin io.Reader) (LineReader, error) {
+	if lp.config.Merge && len(lp.config.Files) > 0 {
+		return OpenMerged(stdin, lp.config.Files, lp.formatter.EntryTime)
 	}
 
-	if err := scanner.Err(); err != nil {
-		// Don't report error if context was cancelled (user pressed Ctrl+C)
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-			return fmt.Errorf("error reading input: %v", err)
+	return OpenInput(ctx, stdin, lp.config.Files, lp.config.Follow)
+}
+
+// processLine formats a single log line, reporting whether it should be shown
+func (lp *LogProcessor) processLine(line string) (string, bool) {
+	if line == "" {
+		return "", false
+	}
+
+	config, formatter := lp.rulesFor(line)
+
+	// Filter before formatting so that hidden entries do not count as the
+	// previous entry of the delta time format. Lines whose level cannot be
+	// parsed are shown (fail open).
+	if config.MinLevel != "" {
+		if shouldShow, err := formatter.ShouldShow(line, config.MinLevel); err == nil && !shouldShow {
+			lp.shownEntry = false
+			return "", false
 		}
 	}
 
-	// Flush output
-	return lp.output.Flush()
-}
+	// Apply field filtering if specified. Lines that are not JSON are shown
+	// only after an entry that matched, so that a stack trace stays with its
+	// entry while plain-text noise is filtered out.
+	if config.Where != nil {
+		matched, ok := config.Where.MatchJSON(line)
+		if ok {
+			lp.shownEntry = matched
+		}
+
+		if !lp.shownEntry {
+			return "", false
+		}
+	}
+
+	formatted, err := formatter.Format(line)
+	if err != nil {
+		// If parsing fails, print the original line in the output format
+		if unparsed, err := formatter.FormatUnparsed(line); err == nil {
+			return unparsed, true
+		}
+
+		return line, true
+	}
 
-// processLine processes a single log line
-func (lp *LogProcessor) processLine(line string) (string, error) {
-	return logparser.ParseAndFormatWithOptions(
-		line,
-		lp.customColors,
-		lp.config.ConvertTimestamps,
-		lp.config.TimestampFieldList,
-	)
+	return formatted, true
 }
@@ -0,0 +1,205 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dougalmatthews/glug/internal/filter"
+	"github.com/dougalmatthews/glug/logparser"
+)
+
+const processorInput = `{"level":"debug","time":1609459200,"msg":"starting","subsystem":"secretstore"}
+not json at all
+
+{"level":"error","time":1609459260,"msg":"request failed","status":503}
+{"level":"warn","time":1609459320,"msg":"slow request","status":200}
+`
+
+// runProcessor runs the whole pipeline over input and returns its output
+func runProcessor(t *testing.T, config *Config, input string) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := NewLogProcessor(config).Process(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Process() error: %v", err)
+	}
+
+	return out.String()
+}
+
+func TestProcess(t *testing.T) {
+	mustParse := func(expr string) *filter.Expr {
+		parsed, err := filter.Parse(expr)
+		if err != nil {
+			t.Fatalf("filter.Parse(%q) error: %v", expr, err)
+		}
+
+		return parsed
+	}
+
+	tests := []struct {
+		name        string
+		config      *Config
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:     "formats every line",
+			config:   &Config{},
+			contains: []string{"DEBUG starting subsystem=secretstore", "not json at all", "ERROR request failed status=503", "WARN slow request"},
+		},
+		{
+			name:        "level filter",
+			config:      &Config{MinLevel: "warn"},
+			contains:    []string{"ERROR request failed", "WARN slow request", "not json at all"},
+			notContains: []string{"starting"},
+		},
+		{
+			name:        "where filter",
+			config:      &Config{Where: mustParse("status >= 500")},
+			contains:    []string{"ERROR request failed"},
+			notContains: []string{"starting", "slow request", "not json at all"},
+		},
+		{
+			name:        "level and where combined",
+			config:      &Config{MinLevel: "warn", Where: mustParse(`msg =~ "slow"`)},
+			contains:    []string{"WARN slow request"},
+			notContains: []string{"request failed", "starting"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runProcessor(t, tt.config, processorInput)
+
+			for _, substr := range tt.contains {
+				if !strings.Contains(result, substr) {
+					t.Errorf("Process() output missing expected substring %q\nGot: %s", substr, result)
+				}
+			}
+
+			for _, substr := range tt.notContains {
+				if strings.Contains(result, substr) {
+					t.Errorf("Process() output contains unexpected substring %q\nGot: %s", substr, result)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessWhereKeepsContinuationLines(t *testing.T) {
+	where, err := filter.Parse("status >= 500")
+	if err != nil {
+		t.Fatalf("filter.Parse() error: %v", err)
+	}
+
+	input := `plain-text banner
+{"level":"error","msg":"request failed","status":503}
+panic: boom
+	at handler.go:12
+{"level":"info","msg":"ok","status":200}
+noise after a hidden entry
+`
+
+	result := runProcessor(t, &Config{Where: where}, input)
+
+	for _, substr := range []string{"request failed", "panic: boom", "\tat handler.go:12"} {
+		if !strings.Contains(result, substr) {
+			t.Errorf("Process() output missing expected substring %q\nGot: %s", substr, result)
+		}
+	}
+
+	for _, substr := range []string{"banner", "ok", "noise"} {
+		if strings.Contains(result, substr) {
+			t.Errorf("Process() output contains unexpected substring %q\nGot: %s", substr, result)
+		}
+	}
+}
+
+func TestProcessSkipsBlankLines(t *testing.T) {
+	result := runProcessor(t, &Config{}, processorInput)
+
+	if lines := strings.Count(result, "\n"); lines != 4 {
+		t.Errorf("Process() wrote %d lines, want 4\nGot: %s", lines, result)
+	}
+}
+
+func TestProcessMergedFilesWithTags(t *testing.T) {
+	dir := t.TempDir()
+	api := filepath.Join(dir, "api.log")
+	worker := filepath.Join(dir, "worker.log")
+
+	appendToFile(t, api, `{"time":1609459200,"msg":"api first"}`+"\n"+`{"time":1609459300,"msg":"api last"}`+"\n")
+	appendToFile(t, worker, `{"time":1609459250,"msg":"worker middle"}`+"\n")
+
+	result := runProcessor(t, &Config{Files: []string{api, worker}, Merge: true, SourceTags: true}, "")
+
+	first := strings.Index(result, "[api.log]    ")
+	middle := strings.Index(result, "[worker.log] ")
+	last := strings.LastIndex(result, "api last")
+
+	if first == -1 || middle == -1 || last == -1 || !(first < middle && middle < last) {
+		t.Errorf("Process() did not merge files in time order with tags\nGot: %s", result)
+	}
+}
+
+func TestProcessProfiles(t *testing.T) {
+	gateway, err := filter.Parse(`program == "gateway"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"level":"debug","msg":"routing","program":"gateway","status":502}
+{"level":"debug","msg":"polling","program":"agent","validUntil":1609459200}
+{"level":"info","msg":"check passed","program":"agent","validUntil":1609459200}
+`
+
+	config := &Config{
+		MinLevel: "info",
+		Profiles: []Profile{{
+			Name:   "gateway",
+			Match:  gateway,
+			Config: &Config{MinLevel: "debug", Fields: logparser.FieldMapping{Message: []string{"program"}}},
+		}},
+	}
+
+	result := runProcessor(t, config, input)
+
+	for _, substr := range []string{"DEBUG gateway", "msg=routing", "INFO check passed"} {
+		if !strings.Contains(result, substr) {
+			t.Errorf("Process() output missing expected substring %q\nGot: %s", substr, result)
+		}
+	}
+
+	if strings.Contains(result, "polling") {
+		t.Errorf("Process() applied the profile level to a line it does not match\nGot: %s", result)
+	}
+}
+
+func TestProcessStructuredOutput(t *testing.T) {
+	result := runProcessor(t, &Config{Output: logparser.OutputCSV, Columns: []string{"level", "msg", "status"}}, processorInput)
+
+	expected := "level,msg,status\ndebug,starting,\n,not json at all,\nerror,request failed,503\nwarn,slow request,200\n"
+	if result != expected {
+		t.Errorf("Process() =\n%s\nwant\n%s", result, expected)
+	}
+}
+
+func TestProcessTimeDelta(t *testing.T) {
+	delta, err := logparser.ParseTimeFormat("delta", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := runProcessor(t, &Config{MinLevel: "info", TimeFormat: delta}, processorInput)
+
+	// The hidden debug entry is not the previous entry of the error
+	for _, substr := range []string{"+0s ERROR request failed", "+1m WARN slow request"} {
+		if !strings.Contains(result, substr) {
+			t.Errorf("Process() output missing expected substring %q\nGot: %s", substr, result)
+		}
+	}
+}
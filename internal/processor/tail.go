@@ -0,0 +1,181 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultPollInterval is how often a followed file is checked for new data
+const defaultPollInterval = 250 * time.Millisecond
+
+// openFollowers tails every path concurrently until ctx is cancelled
+func openFollowers(ctx context.Context, stdin io.Reader, paths []string) (LineReader, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	readers := make([]LineReader, 0, len(paths))
+
+	for _, path := range paths {
+		if path == stdinName {
+			readers = append(readers, NewScannerReader(stdinName, stdin))
+			continue
+		}
+
+		tailer, err := newFileTailer(ctx, path, defaultPollInterval)
+		if err != nil {
+			cancel()
+			closeAll(readers)
+
+			return nil, err
+		}
+
+		readers = append(readers, tailer)
+	}
+
+	return newChannelReader(ctx, cancel, readers), nil
+}
+
+// fileTailer follows a file as it grows, like tail -F. When the file is
+// truncated it is read again from the start, and when it is replaced (for
+// example renamed by logrotate) the old file is drained before the new one
+// is opened.
+type fileTailer struct {
+	ctx          context.Context
+	path         string
+	pollInterval time.Duration
+
+	file    *os.File
+	info    os.FileInfo
+	reader  *bufio.Reader
+	offset  int64
+	partial strings.Builder
+}
+
+// newFileTailer opens path for following
+func newFileTailer(ctx context.Context, path string, pollInterval time.Duration) (*fileTailer, error) {
+	ft := &fileTailer{ctx: ctx, path: path, pollInterval: pollInterval}
+
+	if err := ft.open(); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+
+	return ft, nil
+}
+
+// open (re)opens the file at path and reads it from the start
+func (ft *fileTailer) open() error {
+	file, err := os.Open(ft.path)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if ft.file != nil {
+		_ = ft.file.Close()
+	}
+
+	ft.file = file
+	ft.info = info
+	ft.reader = bufio.NewReader(file)
+	ft.offset = 0
+
+	return nil
+}
+
+// ReadLine blocks until a complete line is available or ctx is cancelled
+func (ft *fileTailer) ReadLine() (Line, error) {
+	for {
+		chunk, err := ft.reader.ReadString('\n')
+		ft.offset += int64(len(chunk))
+		ft.partial.WriteString(chunk)
+
+		if err == nil {
+			text := strings.TrimRight(ft.partial.String(), "\r\n")
+			ft.partial.Reset()
+
+			return Line{Source: ft.path, Text: text}, nil
+		}
+
+		if !errors.Is(err, io.EOF) {
+			return Line{}, fmt.Errorf("error reading %s: %v", ft.path, err)
+		}
+
+		rotated, err := ft.checkRotation()
+		if err != nil {
+			return Line{}, err
+		}
+
+		if rotated {
+			// Anything left without a newline in the old file is complete
+			if ft.partial.Len() > 0 {
+				text := ft.partial.String()
+				ft.partial.Reset()
+
+				return Line{Source: ft.path, Text: text}, nil
+			}
+
+			continue
+		}
+
+		// More data arrived while checking for rotation
+		if ft.reader.Buffered() > 0 {
+			continue
+		}
+
+		select {
+		case <-ft.ctx.Done():
+			return Line{}, io.EOF
+		case <-time.After(ft.pollInterval):
+		}
+	}
+}
+
+// checkRotation detects truncation and replacement of the followed file,
+// reporting whether reading should restart from a new file
+func (ft *fileTailer) checkRotation() (bool, error) {
+	info, err := os.Stat(ft.path)
+	if err != nil {
+		// The file may be briefly missing while it is being rotated
+		return false, nil
+	}
+
+	if !os.SameFile(ft.info, info) {
+		// Drain anything written to the old file since the last read
+		if _, err := ft.reader.Peek(1); err == nil {
+			return false, nil
+		}
+
+		if err := ft.open(); err != nil {
+			return false, nil
+		}
+
+		return true, nil
+	}
+
+	if info.Size() < ft.offset {
+		if _, err := ft.file.Seek(0, io.SeekStart); err != nil {
+			return false, fmt.Errorf("error rewinding %s: %v", ft.path, err)
+		}
+
+		ft.reader.Reset(ft.file)
+		ft.offset = 0
+		ft.partial.Reset()
+	}
+
+	return false, nil
+}
+
+// Close closes the followed file
+func (ft *fileTailer) Close() error {
+	return ft.file.Close()
+}
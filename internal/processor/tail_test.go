@@ -0,0 +1,195 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testPollInterval = 10 * time.Millisecond
+
+// readLineWithin reads a line, failing the test if none arrives in time
+func readLineWithin(t *testing.T, r LineReader) string {
+	t.Helper()
+
+	type result struct {
+		line Line
+		err  error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		line, err := r.ReadLine()
+		done <- result{line, err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatalf("ReadLine() error: %v", res.err)
+		}
+
+		return res.line.Text
+	case <-time.After(2 * time.Second):
+		t.Fatal("ReadLine() timed out")
+		return ""
+	}
+}
+
+func appendToFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestFileTailerFollowsGrowth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendToFile(t, path, "first\n")
+
+	tailer, err := newFileTailer(context.Background(), path, testPollInterval)
+	if err != nil {
+		t.Fatalf("newFileTailer() error: %v", err)
+	}
+	defer func() { _ = tailer.Close() }()
+
+	if got := readLineWithin(t, tailer); got != "first" {
+		t.Errorf("ReadLine() = %q, want %q", got, "first")
+	}
+
+	// A partial line is only returned once it is complete
+	appendToFile(t, path, "sec")
+	time.Sleep(3 * testPollInterval)
+	appendToFile(t, path, "ond\n")
+
+	if got := readLineWithin(t, tailer); got != "second" {
+		t.Errorf("ReadLine() = %q, want %q", got, "second")
+	}
+}
+
+func TestFileTailerTruncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendToFile(t, path, "before truncation\n")
+
+	tailer, err := newFileTailer(context.Background(), path, testPollInterval)
+	if err != nil {
+		t.Fatalf("newFileTailer() error: %v", err)
+	}
+	defer func() { _ = tailer.Close() }()
+
+	readLineWithin(t, tailer)
+
+	if err := os.WriteFile(path, []byte("after\n"), 0o600); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+
+	if got := readLineWithin(t, tailer); got != "after" {
+		t.Errorf("ReadLine() = %q, want %q", got, "after")
+	}
+}
+
+func TestFileTailerRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendToFile(t, path, "old 1\n")
+
+	tailer, err := newFileTailer(context.Background(), path, testPollInterval)
+	if err != nil {
+		t.Fatalf("newFileTailer() error: %v", err)
+	}
+	defer func() { _ = tailer.Close() }()
+
+	readLineWithin(t, tailer)
+
+	// Simulate logrotate: a final write, rename, then a new file
+	appendToFile(t, path, "old 2\n")
+
+	if err := os.Rename(path, filepath.Join(dir, "app.log.1")); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+
+	appendToFile(t, path, "new 1\n")
+
+	for _, want := range []string{"old 2", "new 1"} {
+		if got := readLineWithin(t, tailer); got != want {
+			t.Errorf("ReadLine() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestFileTailerStopsOnCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendToFile(t, path, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tailer, err := newFileTailer(ctx, path, testPollInterval)
+	if err != nil {
+		t.Fatalf("newFileTailer() error: %v", err)
+	}
+	defer func() { _ = tailer.Close() }()
+
+	cancel()
+
+	if _, err := tailer.ReadLine(); err != io.EOF {
+		t.Errorf("ReadLine() error = %v, want io.EOF", err)
+	}
+}
+
+func TestOpenInputReadsFilesInOrder(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a.log")
+	second := filepath.Join(dir, "b.log")
+
+	appendToFile(t, first, "a1\na2\n")
+	appendToFile(t, second, "b1\n")
+
+	input, err := OpenInput(context.Background(), nil, []string{first, second}, false)
+	if err != nil {
+		t.Fatalf("OpenInput() error: %v", err)
+	}
+	defer func() { _ = input.Close() }()
+
+	var got []Line
+
+	for {
+		line, err := input.ReadLine()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("ReadLine() error: %v", err)
+		}
+
+		got = append(got, line)
+	}
+
+	want := []Line{{first, "a1"}, {first, "a2"}, {second, "b1"}}
+	if len(got) != len(want) {
+		t.Fatalf("read %d lines, want %d: %v", len(got), len(want), got)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOpenInputMissingFile(t *testing.T) {
+	if _, err := OpenInput(context.Background(), nil, []string{filepath.Join(t.TempDir(), "missing.log")}, false); err == nil {
+		t.Error("OpenInput() expected error for missing file")
+	}
+}
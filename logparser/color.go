@@ -0,0 +1,53 @@
+package logparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ColorMode controls whether a formatter emits ANSI color codes
+type ColorMode int
+
+const (
+	// ColorAuto is decided by the caller, which should pass ColorAlways or
+	// ColorNever instead. Formatters given ColorAuto do not color output.
+	ColorAuto ColorMode = iota
+	// ColorAlways always colors output
+	ColorAlways
+	// ColorNever never colors output
+	ColorNever
+)
+
+// String returns the name of a color mode
+func (m ColorMode) String() string {
+	switch m {
+	case ColorAuto:
+		return "auto"
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	default:
+		return fmt.Sprintf("ColorMode(%d)", int(m))
+	}
+}
+
+// ParseColorMode converts a color mode name to a ColorMode
+func ParseColorMode(name string) (ColorMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "auto":
+		return ColorAuto, nil
+	case "always":
+		return ColorAlways, nil
+	case "never":
+		return ColorNever, nil
+	default:
+		return ColorAuto, fmt.Errorf("unknown color mode %q (expected auto, always or never)", name)
+	}
+}
+
+// Enabled reports whether output is colored in this mode. Only ColorAlways
+// colors output, so that formatting never depends on the terminal.
+func (m ColorMode) Enabled() bool {
+	return m == ColorAlways
+}
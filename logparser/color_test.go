@@ -0,0 +1,77 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestParseColorMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected ColorMode
+		wantErr  bool
+	}{
+		{"", ColorAuto, false},
+		{"auto", ColorAuto, false},
+		{"Always", ColorAlways, false},
+		{"never", ColorNever, false},
+		{"sometimes", ColorAuto, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseColorMode(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseColorMode(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			if got != tt.expected {
+				t.Errorf("ParseColorMode(%q) = %v, want %v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatterColorMode(t *testing.T) {
+	const input = `{"level":"error","msg":"failed FAIL","user":"alice","stack":"a\nb"}`
+
+	// The formatter setting is independent of the package-global detection
+	oldNoColor := color.NoColor
+
+	defer func() { color.NoColor = oldNoColor }()
+
+	tests := []struct {
+		name      string
+		mode      ColorMode
+		noColor   bool
+		wantColor bool
+	}{
+		{"auto without a terminal", ColorAuto, true, false},
+		{"auto with a terminal", ColorAuto, false, false},
+		{"always", ColorAlways, true, true},
+		{"never", ColorNever, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			color.NoColor = tt.noColor
+
+			opts := Options{Color: tt.mode, CustomColors: map[string]string{"FAIL": "red"}, Expand: true}
+
+			for _, output := range []OutputFormat{OutputText, OutputTable} {
+				opts.Output = output
+
+				result, err := NewFormatter(opts).Format(input)
+				if err != nil {
+					t.Fatalf("Format() error: %v", err)
+				}
+
+				if got := strings.Contains(result, "\x1b["); got != tt.wantColor {
+					t.Errorf("%s output colored = %v, want %v: %q", output, got, tt.wantColor, result)
+				}
+			}
+		})
+	}
+}
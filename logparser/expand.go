@@ -0,0 +1,198 @@
+package logparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// expandIndent is the indentation of expanded field values
+const expandIndent = "    "
+
+// expandedField is a field shown below the header line of an entry
+type expandedField struct {
+	key   string
+	value interface{}
+}
+
+// takeExpandedFields removes the fields to expand from fields and returns
+// them in display order: the fields named in keys first, then, when auto is
+// set, every other field holding an object, an array of objects or arrays,
+// or a multi-line string
+func takeExpandedFields(fields map[string]interface{}, keys []string, auto bool) []expandedField {
+	var expanded []expandedField
+
+	for _, key := range keys {
+		value, ok := lookupPath(fields, key)
+		if !ok {
+			continue
+		}
+
+		deletePath(fields, key)
+		expanded = append(expanded, expandedField{key: key, value: value})
+	}
+
+	if !auto {
+		return expanded
+	}
+
+	var autoKeys []string
+
+	for key, value := range fields {
+		if shouldExpand(value) {
+			autoKeys = append(autoKeys, key)
+		}
+	}
+
+	sort.Strings(autoKeys)
+
+	for _, key := range autoKeys {
+		expanded = append(expanded, expandedField{key: key, value: fields[key]})
+		delete(fields, key)
+	}
+
+	return expanded
+}
+
+// shouldExpand reports whether a value is too large or nested to read on a
+// single line
+func shouldExpand(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return len(v) > 0
+	case []interface{}:
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return true
+			}
+		}
+	case string:
+		return strings.Contains(v, "\n")
+	}
+
+	return false
+}
+
+// formatExpanded renders an expanded field as a "key:" line followed by its
+// value on indented lines. Objects, arrays and strings holding JSON are
+// pretty-printed, stack traces are shown frame by frame and other strings
+// are printed line by line.
+func (f *Formatter) formatExpanded(field expandedField) string {
+	var lines []string
+
+	text, isText := field.value.(string)
+	trace, language := findTrace(text)
+
+	switch {
+	case !isText || isJSONContainer(text):
+		lines = strings.Split(f.colorJSON(indentJSON(field.value)), "\n")
+	case language != traceNone:
+		lines = f.formatTrace(trace, language)
+	default:
+		text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, f.highlight("message", line))
+		}
+	}
+
+	var b strings.Builder
+
+	b.WriteString("  " + f.theme.sprint("key", field.key) + ":")
+
+	for _, line := range lines {
+		b.WriteString("\n" + expandIndent + line)
+	}
+
+	return b.String()
+}
+
+// isJSONContainer reports whether text is a JSON object or array
+func isJSONContainer(text string) bool {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return false
+	}
+
+	return json.Valid([]byte(trimmed))
+}
+
+// indentJSON pretty-prints a value, decoding strings that hold JSON first
+func indentJSON(value interface{}) string {
+	if text, ok := value.(string); ok {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(text), &decoded); err == nil {
+			value = decoded
+		}
+	}
+
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(value); err != nil {
+		return formatValue(value)
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// colorJSON colors the keys, strings, numbers and literals of formatted JSON,
+// highlighting string values
+func (f *Formatter) colorJSON(data string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(data); {
+		c := data[i]
+
+		switch {
+		case c == '"':
+			end := stringEnd(data, i)
+			token := data[i:end]
+
+			if strings.HasPrefix(strings.TrimLeft(data[end:], " "), ":") {
+				b.WriteString(f.theme.sprint("json_key", token))
+			} else {
+				b.WriteString(f.highlight("json_string", token))
+			}
+
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(data) && strings.IndexByte("0123456789.eE+-", data[end]) >= 0 {
+				end++
+			}
+
+			b.WriteString(f.theme.sprint("json_number", data[i:end]))
+			i = end
+		case strings.HasPrefix(data[i:], "true"), strings.HasPrefix(data[i:], "null"):
+			b.WriteString(f.theme.sprint("json_literal", data[i:i+4]))
+			i += 4
+		case strings.HasPrefix(data[i:], "false"):
+			b.WriteString(f.theme.sprint("json_literal", data[i:i+5]))
+			i += 5
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// stringEnd returns the index just past the JSON string starting at start
+func stringEnd(data string, start int) int {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+
+	return len(data)
+}
@@ -0,0 +1,107 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+const expandInput = `{"level":"error","msg":"boom","user":"alice","stack":"goroutine 1 [running]:\nmain.main()\n","req":{"body":"{\"a\":1}","method":"GET"},"tags":["x"]}`
+
+func TestFormatterExpand(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{
+			name: "auto expands objects and multi-line strings",
+			opts: Options{Expand: true},
+			expected: `ERROR boom tags=["x"] user=alice
+  req:
+    {
+      "body": "{\"a\":1}",
+      "method": "GET"
+    }
+  stack:
+    goroutine 1 [running]:
+    main.main()`,
+		},
+		{
+			name: "selected fields only, in the order given",
+			opts: Options{ExpandFields: []string{"stack", "req.body", "missing"}},
+			expected: `ERROR boom req.method=GET tags=["x"] user=alice
+  stack:
+    goroutine 1 [running]:
+    main.main()
+  req.body:
+    {
+      "a": 1
+    }`,
+		},
+		{
+			name:     "no expansion by default",
+			opts:     Options{},
+			expected: `ERROR boom req.body={"a":1} req.method=GET stack=goroutine 1 [running]:` + "\nmain.main()\n" + ` tags=["x"] user=alice`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(tt.opts).Format(expandInput)
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Format() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestShouldExpand(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected bool
+	}{
+		{"object", map[string]interface{}{"a": 1.0}, true},
+		{"empty object", map[string]interface{}{}, false},
+		{"array of objects", []interface{}{map[string]interface{}{}}, true},
+		{"array of scalars", []interface{}{1.0, "a"}, false},
+		{"multi-line string", "a\nb", true},
+		{"single-line string", "a b", false},
+		{"number", 1.0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldExpand(tt.value); got != tt.expected {
+				t.Errorf("shouldExpand(%v) = %v, want %v", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestColorJSON(t *testing.T) {
+	oldNoColor := color.NoColor
+	color.NoColor = false
+
+	defer func() { color.NoColor = oldNoColor }()
+
+	result := NewFormatter(Options{Color: ColorAlways}).colorJSON(`{"key": "va\"l", "n": -1.5e3, "ok": true, "none": null}`)
+
+	for _, want := range []string{
+		color.MagentaString(`"key"`),
+		color.YellowString(`"va\"l"`),
+		color.CyanString("-1.5e3"),
+		color.BlueString("true"),
+		color.BlueString("null"),
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("colorJSON() missing %q\nGot: %q", want, result)
+		}
+	}
+}
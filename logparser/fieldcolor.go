@@ -0,0 +1,265 @@
+package logparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fieldColorOperators are the comparisons of a field color rule, longest
+// first so that >= is not read as >
+var fieldColorOperators = []string{"!=", ">=", "<=", "=", ">", "<"}
+
+// FieldColorRule colors the value of one field when it matches a condition.
+// The level and message keys color the entry's level and message.
+type FieldColorRule struct {
+	field string
+	// op is the comparison, or empty to color every value of the field
+	op    string
+	value string
+	// low and high bound numeric comparisons; a range such as 500..599 sets
+	// both
+	low, high *bound
+	style     Style
+}
+
+// bound is one end of a numeric comparison, either a number or a duration
+type bound struct {
+	number   float64
+	duration bool
+}
+
+// ParseFieldColorRule parses a field color rule of the form
+// FIELD[OP VALUE]:STYLE, such as status=500:red, latency>250ms:yellow or
+// status=500..599:red. OP is one of =, !=, >, >=, < and <=. Equality
+// compares text case-insensitively, or numbers by value; the other
+// comparisons and LOW..HIGH ranges need numbers or durations such as 1.5s.
+// Without OP every value of the field takes the style.
+func ParseFieldColorRule(spec string) (FieldColorRule, error) {
+	cut := strings.LastIndex(spec, ":")
+	if cut < 0 {
+		return FieldColorRule{}, fmt.Errorf("invalid field color rule %q (expected field[op value]:style)", spec)
+	}
+
+	condition, styleSpec := spec[:cut], spec[cut+1:]
+
+	style, err := ParseStyle(styleSpec)
+	if err != nil {
+		return FieldColorRule{}, fmt.Errorf("invalid field color rule %q: %w", spec, err)
+	}
+
+	rule := FieldColorRule{field: strings.TrimSpace(condition), style: style}
+
+	if i := strings.IndexAny(condition, "=!<>"); i >= 0 {
+		rule.field = strings.TrimSpace(condition[:i])
+
+		for _, op := range fieldColorOperators {
+			if strings.HasPrefix(condition[i:], op) {
+				rule.op = op
+				rule.value = strings.TrimSpace(condition[i+len(op):])
+
+				break
+			}
+		}
+
+		if rule.op == "" {
+			return FieldColorRule{}, fmt.Errorf("invalid field color rule %q: unknown operator", spec)
+		}
+
+		if err := rule.parseBounds(); err != nil {
+			return FieldColorRule{}, fmt.Errorf("invalid field color rule %q: %w", spec, err)
+		}
+	}
+
+	if rule.field == "" {
+		return FieldColorRule{}, fmt.Errorf("invalid field color rule %q: missing field", spec)
+	}
+
+	return rule, nil
+}
+
+// parseBounds sets the numeric bounds of the comparisons that need them
+func (r *FieldColorRule) parseBounds() error {
+	switch r.op {
+	case ">", ">=", "<", "<=":
+		value, ok := parseBound(r.value)
+		if !ok {
+			return fmt.Errorf("%q is not a number or duration", r.value)
+		}
+
+		r.low, r.high = value, value
+	case "=", "!=":
+		// Levels are compared by their canonical names, so warning matches WARN
+		if r.field == "level" {
+			r.value = normalizeLevel(r.value)
+		}
+
+		lowText, highText, found := strings.Cut(r.value, "..")
+		if !found {
+			return nil
+		}
+
+		// Values such as a..b that are not ranges are compared as text
+		low, lowOK := parseBound(lowText)
+		high, highOK := parseBound(highText)
+
+		if lowOK && highOK && low.duration == high.duration {
+			r.low, r.high = low, high
+		}
+	}
+
+	return nil
+}
+
+// parseBound parses a number or a duration
+func parseBound(text string) (*bound, bool) {
+	text = strings.TrimSpace(text)
+
+	if number, err := strconv.ParseFloat(text, 64); err == nil {
+		return &bound{number: number}, true
+	}
+
+	if duration, err := time.ParseDuration(text); err == nil {
+		return &bound{number: float64(duration), duration: true}, true
+	}
+
+	return nil, false
+}
+
+// matches reports whether a value of the rule's field meets its condition
+func (r FieldColorRule) matches(value interface{}) bool {
+	if r.op == "" {
+		return true
+	}
+
+	if r.low == nil {
+		same := strings.EqualFold(formatValue(value), r.value)
+
+		if number, ok := toNumber(value); ok {
+			if want, err := strconv.ParseFloat(r.value, 64); err == nil {
+				same = number == want
+			}
+		}
+
+		return same == (r.op == "=")
+	}
+
+	number, ok := r.number(value)
+	if !ok {
+		return false
+	}
+
+	switch r.op {
+	case ">":
+		return number > r.low.number
+	case ">=":
+		return number >= r.low.number
+	case "<":
+		return number < r.high.number
+	case "<=":
+		return number <= r.high.number
+	default:
+		inRange := number >= r.low.number && number <= r.high.number
+		return inRange == (r.op == "=")
+	}
+}
+
+// number converts a value to the kind of number the rule compares with:
+// nanoseconds for duration bounds, otherwise the value as a float
+func (r FieldColorRule) number(value interface{}) (float64, bool) {
+	if !r.low.duration {
+		return toNumber(value)
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+
+	duration, err := time.ParseDuration(strings.TrimSpace(text))
+	if err != nil {
+		return 0, false
+	}
+
+	return float64(duration), true
+}
+
+// toNumber converts a JSON number, or a string holding one, to a float
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// fieldStyle returns the style of the last field color rule for key that
+// matches value, so that later rules take priority
+func (f *Formatter) fieldStyle(key string, value interface{}) (Style, bool) {
+	for i := len(f.fieldColors) - 1; i >= 0; i-- {
+		rule := f.fieldColors[i]
+		if rule.field == key && rule.matches(value) {
+			return rule.style, true
+		}
+	}
+
+	return Style{}, false
+}
+
+// fieldValue renders a field value, styled by styleValue
+func (f *Formatter) fieldValue(key string, value interface{}) string {
+	return f.styleValue(key, value, f.displayValue(key, value))
+}
+
+// styleValue styles text showing the value of a field: in the style of the
+// field color rules when one matches, in the value's own palette color for
+// the HashFields, and otherwise highlighted
+func (f *Formatter) styleValue(key string, value interface{}, text string) string {
+	if style, ok := f.fieldStyle(key, value); ok {
+		return style.Sprint(text)
+	}
+
+	if f.isHashField(key) {
+		return f.theme.hashStyle(formatValue(value)).Sprint(text)
+	}
+
+	return f.highlight("value", text)
+}
+
+// isHashField reports whether key is one of the HashFields
+func (f *Formatter) isHashField(key string) bool {
+	for _, hashField := range f.opts.HashFields {
+		if key == hashField {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatLevel returns a level in upper case, styled by the field color rules
+// for the level key or otherwise by its severity
+func (f *Formatter) formatLevel(level string) string {
+	if style, ok := f.fieldStyle("level", normalizeLevel(level)); ok {
+		return style.Sprint(strings.ToUpper(level))
+	}
+
+	return f.theme.formatLevel(level)
+}
+
+// formatMessage returns the message, styled by the field color rules for the
+// message key or otherwise highlighted
+func (f *Formatter) formatMessage(message string) string {
+	if style, ok := f.fieldStyle("message", message); ok {
+		return style.Sprint(message)
+	}
+
+	return f.highlight("message", message)
+}
@@ -0,0 +1,143 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFieldColorRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		field   string
+		matches []interface{}
+		misses  []interface{}
+	}{
+		{"user:cyan", "user", []interface{}{"alice", 1.0}, nil},
+		{"status=500:red", "status", []interface{}{500.0, "500", "500.0"}, []interface{}{501.0, "5000"}},
+		{"status!=200:red", "status", []interface{}{500.0}, []interface{}{200.0, "200"}},
+		{"result=fail:bold red", "result", []interface{}{"FAIL", "fail"}, []interface{}{"FAILOVER"}},
+		{"latency>500:red", "latency", []interface{}{500.5, "750"}, []interface{}{500.0, "slow", true}},
+		{"latency >= 500 : red", "latency", []interface{}{500.0}, []interface{}{499.9}},
+		{"latency<10:green", "latency", []interface{}{9.0}, []interface{}{10.0}},
+		{"latency<=10:green", "latency", []interface{}{10.0}, []interface{}{10.1}},
+		{"status=500..599:red", "status", []interface{}{500.0, 599.0, "550"}, []interface{}{499.0, 600.0}},
+		{"status!=200..299:red", "status", []interface{}{404.0}, []interface{}{204.0}},
+		{"elapsed>250ms:yellow", "elapsed", []interface{}{"1.5s", "251ms"}, []interface{}{"250ms", 300.0}},
+		{"elapsed=1s..2s:yellow", "elapsed", []interface{}{"1500ms"}, []interface{}{"3s"}},
+		{"path=/a..b:blue", "path", []interface{}{"/a..b"}, []interface{}{"/a"}},
+		{"time=12:30:red", "time", []interface{}{"12:30"}, []interface{}{"12:31"}},
+		{"level=warning:bold red", "level", []interface{}{"WARN"}, []interface{}{"ERROR"}},
+		{"http.status>=500:red", "http.status", []interface{}{503.0}, []interface{}{404.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rule, err := ParseFieldColorRule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseFieldColorRule(%q) error: %v", tt.spec, err)
+			}
+
+			if rule.field != tt.field {
+				t.Errorf("field = %q, expected %q", rule.field, tt.field)
+			}
+
+			for _, value := range tt.matches {
+				if !rule.matches(value) {
+					t.Errorf("rule %q does not match %v", tt.spec, value)
+				}
+			}
+
+			for _, value := range tt.misses {
+				if rule.matches(value) {
+					t.Errorf("rule %q matches %v", tt.spec, value)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFieldColorRuleErrors(t *testing.T) {
+	for _, spec := range []string{"status", "status=500", ":red", "=500:red", "status=500:purple", "latency>fast:red", "a<>b:red"} {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := ParseFieldColorRule(spec); err == nil {
+				t.Errorf("ParseFieldColorRule(%q) expected an error", spec)
+			}
+		})
+	}
+}
+
+func TestFormatterFieldColors(t *testing.T) {
+	const (
+		red  = "\x1b[31m"
+		cyan = "\x1b[36m"
+		end  = "\x1b[0m"
+	)
+
+	var rules []FieldColorRule
+
+	for _, spec := range []string{"status>=500:red", "user:cyan", "level=info:red"} {
+		rule, err := ParseFieldColorRule(spec)
+		if err != nil {
+			t.Fatalf("ParseFieldColorRule(%q) error: %v", spec, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	highlight, err := ParseHighlightRule("red=FAIL")
+	if err != nil {
+		t.Fatalf("ParseHighlightRule error: %v", err)
+	}
+
+	formatter := NewFormatter(Options{Color: ColorAlways, FieldColors: rules, Highlights: []HighlightRule{highlight}})
+
+	tests := []struct {
+		name     string
+		line     string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "numeric threshold",
+			line:     `{"level":"warn","msg":"FAIL","status":503}`,
+			contains: []string{red + "FAIL" + end, "=" + red + "503" + end},
+		},
+		{
+			name:     "below threshold",
+			line:     `{"msg":"ok","status":404}`,
+			excludes: []string{red + "404"},
+		},
+		{
+			name:     "field rule beats highlights",
+			line:     `{"msg":"ok","user":"FAILOVER-BOT"}`,
+			contains: []string{"=" + cyan + "FAILOVER-BOT" + end},
+			excludes: []string{red + "FAIL"},
+		},
+		{
+			name:     "level rule",
+			line:     `{"level":"info","msg":"ok"}`,
+			contains: []string{red + "INFO" + end},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := formatter.Format(tt.line)
+			if err != nil {
+				t.Fatalf("Format error: %v", err)
+			}
+
+			for _, want := range tt.contains {
+				if !strings.Contains(result, want) {
+					t.Errorf("Format(%s) = %q, expected it to contain %q", tt.line, result, want)
+				}
+			}
+
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(result, unwanted) {
+					t.Errorf("Format(%s) = %q, expected it not to contain %q", tt.line, result, unwanted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package logparser
+
+import "strings"
+
+// FieldMapping lists, in order of preference, the keys that hold the level,
+// time and message of a log entry
+type FieldMapping struct {
+	Level   []string
+	Time    []string
+	Message []string
+}
+
+// DefaultFieldMapping returns the keys used by common logging libraries such
+// as zap, logrus, slog, Bunyan/Pino and GCP structured logging
+func DefaultFieldMapping() FieldMapping {
+	return FieldMapping{
+		Level:   []string{"level", "severity", "lvl", "loglevel"},
+		Time:    []string{"time", "ts", "timestamp", "@timestamp"},
+		Message: []string{"message", "msg", "@message"},
+	}
+}
+
+// withDefaults fills any empty key list from DefaultFieldMapping
+func (m FieldMapping) withDefaults() FieldMapping {
+	defaults := DefaultFieldMapping()
+
+	if len(m.Level) == 0 {
+		m.Level = defaults.Level
+	}
+
+	if len(m.Time) == 0 {
+		m.Time = defaults.Time
+	}
+
+	if len(m.Message) == 0 {
+		m.Message = defaults.Message
+	}
+
+	return m
+}
+
+// ParseFieldKeys splits a comma-separated list of keys, dropping empty entries
+func ParseFieldKeys(value string) []string {
+	var keys []string
+
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// lookupField returns the first of keys present in rawLog whose value is
+// accepted by ok, along with the key it was found under. Keys may be dotted
+// paths into nested objects, such as log.level.
+func lookupField(rawLog map[string]interface{}, keys []string, ok func(interface{}) bool) (string, interface{}, bool) {
+	for _, key := range keys {
+		value, exists := lookupPath(rawLog, key)
+		if !exists || !ok(value) {
+			continue
+		}
+
+		return key, value, true
+	}
+
+	return "", nil, false
+}
+
+// lookupPath finds a key in fields. A literal key wins; otherwise a dotted
+// key is followed into nested objects.
+func lookupPath(fields map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := fields[key]; ok {
+		return value, true
+	}
+
+	head, rest, found := strings.Cut(key, ".")
+	if !found {
+		return nil, false
+	}
+
+	nested, ok := fields[head].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	return lookupPath(nested, rest)
+}
+
+// deletePath removes a key found by lookupPath, along with any nested
+// objects left empty by its removal
+func deletePath(fields map[string]interface{}, key string) {
+	if _, ok := fields[key]; ok {
+		delete(fields, key)
+		return
+	}
+
+	head, rest, found := strings.Cut(key, ".")
+	if !found {
+		return
+	}
+
+	nested, ok := fields[head].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	deletePath(nested, rest)
+
+	if len(nested) == 0 {
+		delete(fields, head)
+	}
+}
+
+// isString reports whether value is a JSON string
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+// anyValue accepts every value
+func anyValue(interface{}) bool {
+	return true
+}
@@ -0,0 +1,126 @@
+package logparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatterFieldAliases(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		fields      FieldMapping
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:     "zap keys",
+			input:    `{"level":"info","ts":1609459200,"msg":"Server started","caller":"main.go:12"}`,
+			contains: []string{"INFO", "Server started", "caller=main.go:12"},
+			notContains: []string{
+				"msg=",
+				"ts=",
+			},
+		},
+		{
+			name:        "GCP severity",
+			input:       `{"severity":"ERROR","timestamp":"2023-01-01T12:00:00Z","message":"Request failed"}`,
+			contains:    []string{"ERROR", "2023-01-01 12:00:00", "Request failed"},
+			notContains: []string{"severity=", "timestamp="},
+		},
+		{
+			name:        "primary key wins over alias",
+			input:       `{"message":"primary","msg":"secondary"}`,
+			contains:    []string{"primary", "msg=secondary"},
+			notContains: []string{"message="},
+		},
+		{
+			name:        "custom message key",
+			input:       `{"level":"warn","textPayload":"Disk almost full","message":"ignored"}`,
+			fields:      FieldMapping{Message: []string{"textPayload"}},
+			contains:    []string{"WARN", "Disk almost full", "message=ignored"},
+			notContains: []string{"textPayload="},
+		},
+		{
+			name:     "undecodable level falls through to the next key",
+			input:    `{"level":9,"severity":"ERROR","message":"x"}`,
+			contains: []string{"ERROR", "level=9"},
+		},
+		{
+			name:     "non-string message stays a field",
+			input:    `{"msg":42,"message":"text"}`,
+			contains: []string{"text", "msg=42"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(Options{Fields: tt.fields}).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+
+			for _, substr := range tt.contains {
+				if !strings.Contains(result, substr) {
+					t.Errorf("Format() result missing expected substring %q\nGot: %s", substr, result)
+				}
+			}
+
+			for _, substr := range tt.notContains {
+				if strings.Contains(result, substr) {
+					t.Errorf("Format() result contains unexpected substring %q\nGot: %s", substr, result)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatterShouldShowWithAliases(t *testing.T) {
+	tests := []struct {
+		name       string
+		jsonLine   string
+		fields     FieldMapping
+		minLevel   string
+		shouldShow bool
+	}{
+		{"severity below minimum", `{"severity":"INFO","message":"x"}`, FieldMapping{}, "warning", false},
+		{"severity above minimum", `{"severity":"ERROR","message":"x"}`, FieldMapping{}, "warning", true},
+		{"custom level key", `{"lvl_name":"debug"}`, FieldMapping{Level: []string{"lvl_name"}}, "info", false},
+		{"custom key ignores default", `{"level":"debug"}`, FieldMapping{Level: []string{"lvl_name"}}, "info", true},
+		{"undecodable level falls through", `{"level":9,"severity":"DEBUG"}`, FieldMapping{}, "info", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(Options{Fields: tt.fields}).ShouldShow(tt.jsonLine, tt.minLevel)
+			if err != nil {
+				t.Fatalf("ShouldShow() error: %v", err)
+			}
+
+			if result != tt.shouldShow {
+				t.Errorf("ShouldShow() = %v, want %v", result, tt.shouldShow)
+			}
+		})
+	}
+}
+
+func TestParseFieldKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"", nil},
+		{"msg", []string{"msg"}},
+		{" msg , message ,,", []string{"msg", "message"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := ParseFieldKeys(tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseFieldKeys(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package logparser
+
+import "fmt"
+
+// Flatten returns fields with nested objects flattened into dotted keys, so
+// {"http":{"status":500}} becomes {"http.status":500}. maxDepth limits the
+// number of segments in a flattened key; deeper objects are kept whole.
+// A maxDepth of 0 means no limit and 1 leaves fields unchanged.
+func Flatten(fields map[string]interface{}, maxDepth int) map[string]interface{} {
+	flat := make(map[string]interface{}, len(fields))
+
+	for key, value := range fields {
+		flattenInto(flat, key, value, 1, maxDepth)
+	}
+
+	return flat
+}
+
+// flattenInto adds value to flat under key, descending into non-empty objects
+// until maxDepth is reached
+func flattenInto(flat map[string]interface{}, key string, value interface{}, depth, maxDepth int) {
+	nested, ok := value.(map[string]interface{})
+	if !ok || len(nested) == 0 || (maxDepth > 0 && depth >= maxDepth) {
+		flat[key] = value
+		return
+	}
+
+	for nestedKey, nestedValue := range nested {
+		flattenInto(flat, key+"."+nestedKey, nestedValue, depth+1, maxDepth)
+	}
+}
+
+// formatValue renders a field value for display. Scalars are printed as they
+// are, while arrays and objects are printed as compact JSON.
+func formatValue(value interface{}) string {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		if encoded, err := encodeJSON(value); err == nil {
+			return encoded
+		}
+	}
+
+	return fmt.Sprintf("%v", value)
+}
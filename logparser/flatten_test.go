@@ -0,0 +1,151 @@
+package logparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	fields := map[string]interface{}{
+		"http": map[string]interface{}{
+			"status": 500.0,
+			"request": map[string]interface{}{
+				"method": "GET",
+			},
+		},
+		"tags":  []interface{}{"a", "b"},
+		"empty": map[string]interface{}{},
+		"user":  "alice",
+	}
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		want     map[string]interface{}
+	}{
+		{
+			name:     "no limit",
+			maxDepth: 0,
+			want: map[string]interface{}{
+				"http.status":         500.0,
+				"http.request.method": "GET",
+				"tags":                []interface{}{"a", "b"},
+				"empty":               map[string]interface{}{},
+				"user":                "alice",
+			},
+		},
+		{
+			name:     "depth two keeps deeper objects whole",
+			maxDepth: 2,
+			want: map[string]interface{}{
+				"http.status":  500.0,
+				"http.request": map[string]interface{}{"method": "GET"},
+				"tags":         []interface{}{"a", "b"},
+				"empty":        map[string]interface{}{},
+				"user":         "alice",
+			},
+		},
+		{
+			name:     "depth one leaves fields unchanged",
+			maxDepth: 1,
+			want:     fields,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Flatten(fields, tt.maxDepth); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Flatten(depth %d) = %v, want %v", tt.maxDepth, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{"text", "text"},
+		{42.0, "42"},
+		{true, "true"},
+		{[]interface{}{1.0, "two", nil}, `[1,"two",null]`},
+		{map[string]interface{}{"b": 2.0, "a": "<x>"}, `{"a":"<x>","b":2}`},
+	}
+
+	for _, tt := range tests {
+		if got := formatValue(tt.value); got != tt.expected {
+			t.Errorf("formatValue(%v) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatterFlattensNestedFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		opts        Options
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "nested objects use dotted keys",
+			input:       `{"msg":"request","http":{"status":500,"request":{"method":"GET"}}}`,
+			contains:    []string{"http.request.method=GET", "http.status=500"},
+			notContains: []string{"map["},
+		},
+		{
+			name:     "arrays render as JSON",
+			input:    `{"msg":"tagged","tags":["a","b"],"ids":[1,2]}`,
+			contains: []string{`ids=[1,2]`, `tags=["a","b"]`},
+		},
+		{
+			name:     "depth limit",
+			input:    `{"msg":"request","http":{"request":{"method":"GET"}}}`,
+			opts:     Options{FlattenDepth: 2},
+			contains: []string{`http.request={"method":"GET"}`},
+		},
+		{
+			name:     "dotted timestamp field",
+			input:    `{"msg":"cert","cert":{"expires":1609459200}}`,
+			opts:     Options{ConvertTimestamps: true, TimestampFields: []string{"cert.expires"}},
+			contains: []string{"cert.expires=2021-01-01"},
+		},
+		{
+			name:        "dotted field mapping",
+			input:       `{"log":{"level":"error","origin":"main.go"},"message":"failed"}`,
+			opts:        Options{Fields: FieldMapping{Level: []string{"log.level"}}},
+			contains:    []string{"ERROR failed", "log.origin=main.go"},
+			notContains: []string{"log.level="},
+		},
+		{
+			name:        "emptied parent objects are dropped",
+			input:       `{"log":{"level":"warn"},"message":"slow"}`,
+			opts:        Options{Fields: FieldMapping{Level: []string{"log.level"}}},
+			contains:    []string{"WARN slow"},
+			notContains: []string{"log="},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(tt.opts).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+
+			for _, substr := range tt.contains {
+				if !strings.Contains(result, substr) {
+					t.Errorf("Format() result missing expected substring %q\nGot: %s", substr, result)
+				}
+			}
+
+			for _, substr := range tt.notContains {
+				if strings.Contains(result, substr) {
+					t.Errorf("Format() result contains unexpected substring %q\nGot: %s", substr, result)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package logparser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HighlightRule colors the parts of messages and field values that match a
+// pattern. When the pattern has capture groups only the groups are colored.
+type HighlightRule struct {
+	pattern *regexp.Regexp
+	// styles are the styles of the match, or of each capture group in turn;
+	// when empty the theme's highlight style is used
+	styles []Style
+}
+
+// ParseHighlightRule parses a highlight rule of the form
+// [STYLES=][KIND[/FLAGS]:]PATTERN. KIND is re for a regular expression,
+// text for literal text (the default) or word for literal text matched as
+// a whole word. FLAGS are i for case-insensitive and w for whole-word
+// matching. STYLES is a comma-separated list of styles, see ParseStyle; with
+// capture groups, each group takes the next style and the last style is
+// reused for the remaining groups.
+func ParseHighlightRule(spec string) (HighlightRule, error) {
+	rule := spec
+
+	var styles []Style
+
+	if stylesSpec, rest, found := strings.Cut(spec, "="); found && !hasHighlightKind(spec) {
+		parsed, err := parseStyles(stylesSpec)
+		if err == nil {
+			styles, spec = parsed, rest
+		}
+	}
+
+	kind, flags, pattern := "text", "", spec
+
+	if head, rest, found := strings.Cut(spec, ":"); found && hasHighlightKind(spec) {
+		kind, flags, _ = strings.Cut(head, "/")
+		pattern = rest
+	}
+
+	if pattern == "" {
+		return HighlightRule{}, fmt.Errorf("invalid highlight rule %q: empty pattern", rule)
+	}
+
+	var expr string
+
+	switch kind {
+	case "re":
+		expr = pattern
+	case "text":
+		expr = regexp.QuoteMeta(pattern)
+	case "word":
+		expr = regexp.QuoteMeta(pattern)
+		flags += "w"
+	}
+
+	for _, flag := range flags {
+		switch flag {
+		case 'i':
+			expr = "(?i)" + expr
+		case 'w':
+			if kind == "re" {
+				expr = `\b(?:` + expr + `)\b`
+			} else {
+				expr = wordBoundaries(pattern, expr)
+			}
+		default:
+			return HighlightRule{}, fmt.Errorf("invalid highlight rule %q: unknown flag %q (expected i or w)", rule, flag)
+		}
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return HighlightRule{}, fmt.Errorf("invalid highlight rule %q: %w", rule, err)
+	}
+
+	return HighlightRule{pattern: re, styles: styles}, nil
+}
+
+// hasHighlightKind reports whether a rule starts with a KIND[/FLAGS]: prefix
+func hasHighlightKind(spec string) bool {
+	head, _, found := strings.Cut(spec, ":")
+	if !found {
+		return false
+	}
+
+	kind, _, _ := strings.Cut(head, "/")
+
+	return kind == "re" || kind == "text" || kind == "word"
+}
+
+// parseStyles parses a comma-separated list of styles
+func parseStyles(spec string) ([]Style, error) {
+	var styles []Style
+
+	for _, part := range strings.Split(spec, ",") {
+		style, err := ParseStyle(part)
+		if err != nil {
+			return nil, err
+		}
+
+		styles = append(styles, style)
+	}
+
+	return styles, nil
+}
+
+// wordBoundaries anchors expr, which matches pattern, so that it only matches
+// whole words. Ends of the pattern that are not word characters need no
+// anchor.
+func wordBoundaries(pattern, expr string) string {
+	first, _ := utf8.DecodeRuneInString(pattern)
+	last, _ := utf8.DecodeLastRuneInString(pattern)
+
+	if isWordRune(first) {
+		expr = `\b` + expr
+	}
+
+	if isWordRune(last) {
+		expr += `\b`
+	}
+
+	return "(?:" + expr + ")"
+}
+
+// isWordRune reports whether r is a word character for whole-word matching
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// colorRules converts the CustomColors word rules into highlight rules,
+// longest word last so that a word wins over the shorter words inside it
+func colorRules(customColors map[string]string) []HighlightRule {
+	words := make([]string, 0, len(customColors))
+	for word := range customColors {
+		words = append(words, word)
+	}
+
+	sort.Slice(words, func(i, j int) bool {
+		if len(words[i]) != len(words[j]) {
+			return len(words[i]) < len(words[j])
+		}
+
+		return words[i] < words[j]
+	})
+
+	rules := make([]HighlightRule, 0, len(words))
+
+	for _, word := range words {
+		// Unknown colors fall back to white, as they always have
+		style, err := ParseStyle(customColors[word])
+		if err != nil {
+			style = defaultTheme.styles["level_unknown"]
+		}
+
+		rules = append(rules, HighlightRule{pattern: regexp.MustCompile(regexp.QuoteMeta(word)), styles: []Style{style}})
+	}
+
+	return rules
+}
+
+// highlightSpan is a part of a text colored by a highlight rule
+type highlightSpan struct {
+	start, end int
+	style      Style
+}
+
+// highlight styles text in the style of role, except for the parts matched
+// by the highlight rules, which take the rule's style. Rules are matched
+// against the raw text from last to first; a match overlapping one from a
+// later rule is skipped, so later rules take priority.
+func (f *Formatter) highlight(role, text string) string {
+	var spans []highlightSpan
+
+	for i := len(f.highlights) - 1; i >= 0; i-- {
+		rule := f.highlights[i]
+
+		for _, match := range rule.pattern.FindAllStringSubmatchIndex(text, -1) {
+			for _, span := range rule.spans(match, f.theme.styles["highlight"]) {
+				if span.start < span.end && !overlapsSpan(spans, span) {
+					spans = append(spans, span)
+				}
+			}
+		}
+	}
+
+	if len(spans) == 0 {
+		return f.theme.sprint(role, text)
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var (
+		b   strings.Builder
+		pos int
+	)
+
+	for _, span := range spans {
+		if pos < span.start {
+			b.WriteString(f.theme.sprint(role, text[pos:span.start]))
+		}
+
+		b.WriteString(span.style.Sprint(text[span.start:span.end]))
+		pos = span.end
+	}
+
+	if pos < len(text) {
+		b.WriteString(f.theme.sprint(role, text[pos:]))
+	}
+
+	return b.String()
+}
+
+// spans returns the parts of a match to color: the capture groups that took
+// part in the match or, without groups, the whole match
+func (r HighlightRule) spans(match []int, fallback Style) []highlightSpan {
+	styleFor := func(i int) Style {
+		if len(r.styles) == 0 {
+			return fallback
+		}
+
+		return r.styles[min(i, len(r.styles)-1)]
+	}
+
+	if len(match) == 2 {
+		return []highlightSpan{{start: match[0], end: match[1], style: styleFor(0)}}
+	}
+
+	var spans []highlightSpan
+
+	for group := 1; group < len(match)/2; group++ {
+		start, end := match[2*group], match[2*group+1]
+		if start >= 0 {
+			spans = append(spans, highlightSpan{start: start, end: end, style: styleFor(group - 1)})
+		}
+	}
+
+	return spans
+}
+
+// overlapsSpan reports whether span overlaps any of spans
+func overlapsSpan(spans []highlightSpan, span highlightSpan) bool {
+	for _, other := range spans {
+		if span.start < other.end && other.start < span.end {
+			return true
+		}
+	}
+
+	return false
+}
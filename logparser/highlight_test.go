@@ -0,0 +1,138 @@
+package logparser
+
+import "testing"
+
+func TestParseHighlightRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		matches []string
+		misses  []string
+	}{
+		{"FAIL", []string{"FAIL", "FAILOVER"}, []string{"fail"}},
+		{"text:a.b", []string{"a.b"}, []string{"axb"}},
+		{"text/i:fail", []string{"FAIL", "Failed"}, []string{"fial"}},
+		{"word:FAIL", []string{"FAIL", "a FAIL."}, []string{"FAILOVER", "xFAIL"}},
+		{"word/i:fail", []string{"Fail!"}, []string{"failover"}},
+		{"text/w:#tag", []string{"a #tag b"}, []string{"a #tags"}},
+		{`re:user_id=\d+`, []string{"user_id=42"}, []string{"user_id=x"}},
+		{`re/iw:id\d`, []string{"ID1 ok"}, []string{"xid1"}},
+		{"red=word:ERROR", []string{"ERROR"}, []string{"ERRORS"}},
+		{"bold red,#ff8800=re:(a)(b)", []string{"ab"}, []string{"ba"}},
+		{"user_id=42", []string{"user_id=42"}, []string{"user_id"}},
+		{"http://example.com", []string{"http://example.com"}, []string{"http://example"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			rule, err := ParseHighlightRule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseHighlightRule(%q) error: %v", tt.spec, err)
+			}
+
+			for _, text := range tt.matches {
+				if !rule.pattern.MatchString(text) {
+					t.Errorf("rule %q does not match %q", tt.spec, text)
+				}
+			}
+
+			for _, text := range tt.misses {
+				if rule.pattern.MatchString(text) {
+					t.Errorf("rule %q matches %q", tt.spec, text)
+				}
+			}
+		})
+	}
+}
+
+func TestParseHighlightRuleErrors(t *testing.T) {
+	for _, spec := range []string{"", "re:", "red=", "re:(", "text/x:a"} {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := ParseHighlightRule(spec); err == nil {
+				t.Errorf("ParseHighlightRule(%q) expected an error", spec)
+			}
+		})
+	}
+}
+
+func TestFormatterHighlight(t *testing.T) {
+	const (
+		red   = "\x1b[31m"
+		green = "\x1b[32m"
+		blue  = "\x1b[34m"
+		white = "\x1b[37m"
+		hl    = "\x1b[1;30;43m"
+		end   = "\x1b[0m"
+	)
+
+	tests := []struct {
+		name     string
+		rules    []string
+		colors   map[string]string
+		text     string
+		expected string
+	}{
+		{
+			name:     "no rules",
+			text:     "plain",
+			expected: white + "plain" + end,
+		},
+		{
+			name:     "theme highlight style",
+			rules:    []string{"word:b"},
+			text:     "a b c",
+			expected: white + "a " + end + hl + "b" + end + white + " c" + end,
+		},
+		{
+			name:     "capture groups only",
+			rules:    []string{`red,blue=re:(\w+)=(\d+)`},
+			text:     "id=42 x",
+			expected: red + "id" + end + white + "=" + end + blue + "42" + end + white + " x" + end,
+		},
+		{
+			name:     "later rules win overlaps",
+			rules:    []string{"green=FAIL", "red=FAILOVER"},
+			text:     "FAILOVER FAIL",
+			expected: red + "FAILOVER" + end + white + " " + end + green + "FAIL" + end,
+		},
+		{
+			name:     "color rules match inside escape-free text only",
+			colors:   map[string]string{"31": "green", "m": "blue"},
+			text:     "m31",
+			expected: blue + "m" + end + green + "31" + end,
+		},
+		{
+			name:     "longer color words win",
+			colors:   map[string]string{"FAIL": "red", "FAILED": "green"},
+			text:     "FAILED",
+			expected: green + "FAILED" + end,
+		},
+		{
+			name:     "highlight rules beat color rules",
+			rules:    []string{"blue=FAIL"},
+			colors:   map[string]string{"FAIL": "red"},
+			text:     "FAIL",
+			expected: blue + "FAIL" + end,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []HighlightRule
+
+			for _, spec := range tt.rules {
+				rule, err := ParseHighlightRule(spec)
+				if err != nil {
+					t.Fatalf("ParseHighlightRule(%q) error: %v", spec, err)
+				}
+
+				rules = append(rules, rule)
+			}
+
+			formatter := NewFormatter(Options{Highlights: rules, CustomColors: tt.colors, Color: ColorAlways})
+
+			if got := formatter.highlight("message", tt.text); got != tt.expected {
+				t.Errorf("highlight() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
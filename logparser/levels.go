@@ -0,0 +1,117 @@
+package logparser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LevelScheme selects how numeric log levels are decoded
+type LevelScheme int
+
+const (
+	// SchemeAuto decodes 0-7 as syslog severities and 10 and above as Bunyan/Pino levels
+	SchemeAuto LevelScheme = iota
+	// SchemeBunyan decodes Bunyan and Pino levels (10 trace ... 60 fatal)
+	SchemeBunyan
+	// SchemeSyslog decodes syslog severities (0 emergency ... 7 debug)
+	SchemeSyslog
+)
+
+// String returns the name of a level scheme
+func (s LevelScheme) String() string {
+	switch s {
+	case SchemeAuto:
+		return "auto"
+	case SchemeBunyan:
+		return "bunyan"
+	case SchemeSyslog:
+		return "syslog"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLevelScheme converts a scheme name to a LevelScheme
+func ParseLevelScheme(name string) (LevelScheme, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "auto":
+		return SchemeAuto, nil
+	case "bunyan", "pino":
+		return SchemeBunyan, nil
+	case "syslog":
+		return SchemeSyslog, nil
+	default:
+		return SchemeAuto, fmt.Errorf("unknown level scheme %q", name)
+	}
+}
+
+// syslogLevels maps syslog severities to level names
+var syslogLevels = []string{"EMERG", "ALERT", "CRIT", "ERROR", "WARN", "NOTICE", "INFO", "DEBUG"}
+
+// decodeNumericLevel converts a numeric level to a level name using the scheme
+func decodeNumericLevel(value float64, scheme LevelScheme) (string, bool) {
+	if scheme == SchemeAuto {
+		if value >= 10 {
+			scheme = SchemeBunyan
+		} else {
+			scheme = SchemeSyslog
+		}
+	}
+
+	switch scheme {
+	case SchemeBunyan:
+		switch {
+		case value < 10:
+			return "", false
+		case value < 20:
+			return "TRACE", true
+		case value < 30:
+			return "DEBUG", true
+		case value < 40:
+			return "INFO", true
+		case value < 50:
+			return "WARN", true
+		case value < 60:
+			return "ERROR", true
+		default:
+			return "FATAL", true
+		}
+	case SchemeSyslog:
+		if value < 0 || value >= float64(len(syslogLevels)) || value != math.Trunc(value) {
+			return "", false
+		}
+
+		return syslogLevels[int(value)], true
+	default:
+		return "", false
+	}
+}
+
+// levelName returns the level name for a string or numeric level value.
+// Strings holding a number are decoded like numbers.
+func levelName(value interface{}, scheme LevelScheme) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			if name, ok := decodeNumericLevel(n, scheme); ok {
+				return name, true
+			}
+		}
+
+		return v, true
+	case float64:
+		return decodeNumericLevel(v, scheme)
+	default:
+		return "", false
+	}
+}
+
+// isLevelValue accepts values that levelName can decode with the
+// formatter's level scheme, so that a lookup falls through to the next level
+// key when one holds an unknown number
+func (f *Formatter) isLevelValue(value interface{}) bool {
+	_, ok := levelName(value, f.opts.LevelScheme)
+	return ok
+}
@@ -0,0 +1,131 @@
+package logparser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseLevelScheme(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected LevelScheme
+		wantErr  bool
+	}{
+		{"", SchemeAuto, false},
+		{"auto", SchemeAuto, false},
+		{"bunyan", SchemeBunyan, false},
+		{"PINO", SchemeBunyan, false},
+		{"syslog", SchemeSyslog, false},
+		{"log4j", SchemeAuto, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseLevelScheme(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevelScheme(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+
+			if result != tt.expected {
+				t.Errorf("ParseLevelScheme(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeNumericLevel(t *testing.T) {
+	tests := []struct {
+		value    float64
+		scheme   LevelScheme
+		expected string
+		ok       bool
+	}{
+		{10, SchemeBunyan, "TRACE", true},
+		{20, SchemeBunyan, "DEBUG", true},
+		{30, SchemeBunyan, "INFO", true},
+		{35, SchemeBunyan, "INFO", true},
+		{40, SchemeBunyan, "WARN", true},
+		{50, SchemeBunyan, "ERROR", true},
+		{60, SchemeBunyan, "FATAL", true},
+		{5, SchemeBunyan, "", false},
+		{0, SchemeSyslog, "EMERG", true},
+		{3, SchemeSyslog, "ERROR", true},
+		{5, SchemeSyslog, "NOTICE", true},
+		{7, SchemeSyslog, "DEBUG", true},
+		{8, SchemeSyslog, "", false},
+		{2.5, SchemeSyslog, "", false},
+		{4, SchemeAuto, "WARN", true},
+		{40, SchemeAuto, "WARN", true},
+		{8, SchemeAuto, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s_%v", tt.scheme, tt.value), func(t *testing.T) {
+			result, ok := decodeNumericLevel(tt.value, tt.scheme)
+			if result != tt.expected || ok != tt.ok {
+				t.Errorf("decodeNumericLevel(%v, %v) = (%q, %v), want (%q, %v)", tt.value, tt.scheme, result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFormatNumericLevels(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		scheme   LevelScheme
+		contains []string
+	}{
+		{"pino", `{"level":30,"time":1609459200000,"msg":"hello"}`, SchemeAuto, []string{"INFO", "hello"}},
+		{"bunyan fatal", `{"level":60,"msg":"boom"}`, SchemeBunyan, []string{"FATAL", "boom"}},
+		{"syslog", `{"level":3,"msg":"disk error"}`, SchemeSyslog, []string{"ERROR", "disk error"}},
+		{"numeric string", `{"level":"40","msg":"careful"}`, SchemeAuto, []string{"WARN", "careful"}},
+		{"undecodable kept as field", `{"level":9,"msg":"odd"}`, SchemeAuto, []string{"odd", "level=9"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(Options{LevelScheme: tt.scheme}).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+
+			for _, substr := range tt.contains {
+				if !strings.Contains(result, substr) {
+					t.Errorf("Format() result missing expected substring %q\nGot: %s", substr, result)
+				}
+			}
+		})
+	}
+}
+
+func TestShouldShowNumericLevels(t *testing.T) {
+	tests := []struct {
+		name       string
+		jsonLine   string
+		scheme     LevelScheme
+		minLevel   string
+		shouldShow bool
+	}{
+		{"pino info below warn", `{"level":30}`, SchemeAuto, "warn", false},
+		{"pino error above warn", `{"level":50}`, SchemeAuto, "warn", true},
+		{"syslog debug below info", `{"level":7}`, SchemeSyslog, "info", false},
+		{"syslog crit above error", `{"level":2}`, SchemeSyslog, "error", true},
+		{"undecodable level shown", `{"level":9}`, SchemeSyslog, "error", true},
+		{"boolean level shown", `{"level":true}`, SchemeAuto, "error", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(Options{LevelScheme: tt.scheme}).ShouldShow(tt.jsonLine, tt.minLevel)
+			if err != nil {
+				t.Fatalf("ShouldShow() error: %v", err)
+			}
+
+			if result != tt.shouldShow {
+				t.Errorf("ShouldShow() = %v, want %v", result, tt.shouldShow)
+			}
+		})
+	}
+}
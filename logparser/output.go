@@ -0,0 +1,517 @@
+package logparser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode"
+)
+
+// OutputFormat selects how formatted entries are written
+type OutputFormat int
+
+const (
+	// OutputText is the colored human-readable layout
+	OutputText OutputFormat = iota
+	// OutputLogfmt writes key=value pairs
+	OutputLogfmt
+	// OutputJSON writes one normalized JSON object per entry
+	OutputJSON
+	// OutputCSV writes the selected columns as comma-separated values
+	OutputCSV
+	// OutputTSV writes the selected columns as tab-separated values
+	OutputTSV
+	// OutputTemplate executes a text/template for each entry
+	OutputTemplate
+	// OutputTable is the colored layout with fixed-width columns
+	OutputTable
+)
+
+// String returns the name of an output format
+func (o OutputFormat) String() string {
+	switch o {
+	case OutputText:
+		return "text"
+	case OutputLogfmt:
+		return "logfmt"
+	case OutputJSON:
+		return "json"
+	case OutputCSV:
+		return "csv"
+	case OutputTSV:
+		return "tsv"
+	case OutputTemplate:
+		return "template"
+	case OutputTable:
+		return "table"
+	default:
+		return fmt.Sprintf("OutputFormat(%d)", int(o))
+	}
+}
+
+// IsText reports whether the output format is one of the human-readable
+// layouts rather than a structured format
+func (o OutputFormat) IsText() bool {
+	return o == OutputText || o == OutputTable
+}
+
+// ParseOutputFormat converts an output format name to an OutputFormat
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "text":
+		return OutputText, nil
+	case "logfmt":
+		return OutputLogfmt, nil
+	case "json":
+		return OutputJSON, nil
+	case "csv":
+		return OutputCSV, nil
+	case "tsv":
+		return OutputTSV, nil
+	case "template":
+		return OutputTemplate, nil
+	case "table":
+		return OutputTable, nil
+	default:
+		return OutputText, fmt.Errorf("unknown output format %q", name)
+	}
+}
+
+// DefaultColumns are the columns written by the CSV and TSV formats when
+// none are chosen
+var DefaultColumns = []string{"time", "level", "message"}
+
+// ParseTemplate compiles an output template. Templates are executed with a
+// Record, for example "{{.Time}} [{{.Level}}] {{.Message}} {{.Fields.caller}}".
+// Fields the template reads that an entry does not have are empty.
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("output").Option("missingkey=zero").Parse(text)
+}
+
+// Record is the normalized form of a log entry written by the structured
+// output formats and passed to templates
+type Record struct {
+	// Time is the entry time in RFC 3339 format, or as logged when it could
+	// not be parsed
+	Time string
+	// Level is the canonical level name, such as WARN. The json, logfmt, csv
+	// and tsv formats write it in lower case.
+	Level string
+	// Message is the log message
+	Message string
+	// Fields holds the remaining fields, with nested objects left intact
+	Fields map[string]interface{}
+}
+
+// newRecord normalizes an entry, converting the configured timestamp fields
+// and, with AutoTimestamps, those that look like timestamps
+func (f *Formatter) newRecord(entry LogEntry) Record {
+	record := Record{
+		Time:    f.normalizeTime(entry.Time),
+		Level:   normalizeLevel(entry.Level),
+		Message: entry.Message,
+		Fields:  entry.Other,
+	}
+
+	if !f.opts.ConvertTimestamps {
+		return record
+	}
+
+	convert := func(key string, value interface{}) {
+		if parsed, ok := f.parseTime(value); ok {
+			setPath(record.Fields, key, f.inLocation(parsed).Format(time.RFC3339Nano))
+		}
+	}
+
+	for _, key := range f.opts.TimestampFields {
+		if value, ok := lookupPath(record.Fields, key); ok {
+			convert(key, value)
+		}
+	}
+
+	if f.opts.AutoTimestamps {
+		for key, value := range Flatten(record.Fields, 0) {
+			if f.isTimestamp(key, value) {
+				convert(key, value)
+			}
+		}
+	}
+
+	return record
+}
+
+// normalizeTime formats a parsed time as RFC 3339 in the Location, leaving
+// other values as logged
+func (f *Formatter) normalizeTime(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	if parsed, ok := f.parseTime(value); ok {
+		return f.inLocation(parsed).Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// normalizeLevel returns the canonical name of a level, or the level in
+// upper case when it is not recognized
+func normalizeLevel(level string) string {
+	if level == "" {
+		return ""
+	}
+
+	if logLevel, ok := lookupLogLevel(level); ok {
+		return logLevel.String()
+	}
+
+	return strings.ToUpper(level)
+}
+
+// setPath replaces the value of a key found by lookupPath
+func setPath(fields map[string]interface{}, key string, value interface{}) {
+	if _, ok := fields[key]; ok {
+		fields[key] = value
+		return
+	}
+
+	head, rest, found := strings.Cut(key, ".")
+	if !found {
+		return
+	}
+
+	if nested, ok := fields[head].(map[string]interface{}); ok {
+		setPath(nested, rest, value)
+	}
+}
+
+// Header returns the header line of the output format, if it has one
+func (f *Formatter) Header() (string, bool) {
+	switch f.opts.Output {
+	case OutputCSV, OutputTSV:
+		return f.writeDelimited(f.columns()), true
+	default:
+		return "", false
+	}
+}
+
+// FormatUnparsed formats a line that is not JSON. The text and table layouts
+// show it unchanged; the other formats treat it as an entry with only a
+// message.
+func (f *Formatter) FormatUnparsed(line string) (string, error) {
+	if f.opts.Output.IsText() {
+		return line, nil
+	}
+
+	return f.formatStructured(LogEntry{Message: line, Other: map[string]interface{}{}})
+}
+
+// formatStructured formats an entry in one of the structured output formats
+func (f *Formatter) formatStructured(entry LogEntry) (string, error) {
+	record := f.newRecord(entry)
+
+	switch f.opts.Output {
+	case OutputLogfmt:
+		return f.formatLogfmt(record), nil
+	case OutputJSON:
+		return f.formatJSON(record)
+	case OutputCSV, OutputTSV:
+		return f.writeDelimited(recordColumns(record, f.columns())), nil
+	case OutputTemplate:
+		return executeTemplate(f.opts.Template, record, f.templateFields)
+	default:
+		return "", fmt.Errorf("unsupported output format %s", f.opts.Output)
+	}
+}
+
+// columns returns the CSV and TSV columns
+func (f *Formatter) columns() []string {
+	if len(f.opts.Columns) == 0 {
+		return DefaultColumns
+	}
+
+	return f.opts.Columns
+}
+
+// recordColumns returns the values of columns in a record. The time, level
+// and message columns name the normalized fields; other columns are field
+// keys, which may be dotted paths into nested objects.
+func recordColumns(record Record, columns []string) []string {
+	values := make([]string, len(columns))
+
+	for i, column := range columns {
+		switch column {
+		case "time":
+			values[i] = record.Time
+		case "level":
+			values[i] = strings.ToLower(record.Level)
+		case "message", "msg":
+			values[i] = record.Message
+		default:
+			if value, ok := lookupPath(record.Fields, column); ok {
+				values[i] = formatValue(value)
+			}
+		}
+	}
+
+	return values
+}
+
+// writeDelimited writes a row of CSV or TSV values
+func (f *Formatter) writeDelimited(values []string) string {
+	var buf bytes.Buffer
+
+	writer := csv.NewWriter(&buf)
+	if f.opts.Output == OutputTSV {
+		writer.Comma = '\t'
+	}
+
+	// Writing to a bytes.Buffer cannot fail
+	_ = writer.Write(values)
+	writer.Flush()
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// formatLogfmt writes a record as logfmt, with nested objects flattened into
+// dotted keys and only the fields chosen by ShowFields
+func (f *Formatter) formatLogfmt(record Record) string {
+	var pairs []string
+
+	for _, pair := range [][2]string{{"time", record.Time}, {"level", strings.ToLower(record.Level)}, {"msg", record.Message}} {
+		if pair[1] != "" {
+			pairs = append(pairs, pair[0]+"="+logfmtValue(pair[1]))
+		}
+	}
+
+	fields := Flatten(record.Fields, f.opts.FlattenDepth)
+	for _, key := range f.selectKeys(fields) {
+		pairs = append(pairs, fieldKey(key, "time", "level", "msg")+"="+logfmtValue(formatValue(fields[key])))
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// logfmtValue quotes a logfmt value when it is empty or contains spaces,
+// quotes, equals signs or control characters
+func logfmtValue(value string) string {
+	needsQuotes := value == "" || strings.IndexFunc(value, func(r rune) bool {
+		return r == '"' || r == '=' || unicode.IsSpace(r) || unicode.IsControl(r)
+	}) >= 0
+
+	if needsQuotes {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+// formatJSON writes a record as a JSON object with the time, level and
+// message first, followed by the fields chosen by ShowFields in key order
+func (f *Formatter) formatJSON(record Record) (string, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	first := true
+	write := func(key string, value interface{}) error {
+		if !first {
+			buf.WriteByte(',')
+		}
+
+		first = false
+
+		encoded, err := encodeJSON(key)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(encoded + ":")
+
+		encoded, err = encodeJSON(value)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(encoded)
+
+		return nil
+	}
+
+	for _, pair := range [][2]string{{"time", record.Time}, {"level", strings.ToLower(record.Level)}, {"message", record.Message}} {
+		if pair[1] == "" {
+			continue
+		}
+
+		if err := write(pair[0], pair[1]); err != nil {
+			return "", err
+		}
+	}
+
+	fields := f.selectFields(record.Fields, "")
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := write(fieldKey(key, "time", "level", "message"), fields[key]); err != nil {
+			return "", err
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return buf.String(), nil
+}
+
+// fieldKey returns the key a field is written under in a structured format.
+// Fields named after one of the format's own keys, such as a msg field left
+// over when the message came from message, are prefixed with "fields." so
+// that no key is written twice.
+func fieldKey(key string, reserved ...string) string {
+	for _, name := range reserved {
+		if key == name {
+			return "fields." + key
+		}
+	}
+
+	return key
+}
+
+// encodeJSON encodes a value as compact JSON without escaping HTML characters
+func encodeJSON(value interface{}) (string, error) {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		return "", fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// executeTemplate runs an output template for a record, first setting the
+// fields at paths that the record does not have to empty strings
+func executeTemplate(tmpl *template.Template, record Record, paths [][]string) (string, error) {
+	if tmpl == nil {
+		return "", fmt.Errorf("no output template given")
+	}
+
+	if record.Fields == nil {
+		record.Fields = make(map[string]interface{})
+	}
+
+	for _, path := range paths {
+		setMissing(record.Fields, path)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, record); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// templateFieldPaths returns the paths of the fields a template reads from
+// .Fields, such as [http status] for {{.Fields.http.status}}. Lookups in a
+// map print "<no value>" for missing keys even with missingkey=zero, so
+// these fields are given empty values before the template runs.
+func templateFieldPaths(tmpl *template.Template) [][]string {
+	if tmpl == nil {
+		return nil
+	}
+
+	var paths [][]string
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(&n.BranchNode)
+		case *parse.RangeNode:
+			walk(&n.BranchNode)
+		case *parse.WithNode:
+			walk(&n.BranchNode)
+		case *parse.BranchNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "Fields" {
+				paths = append(paths, n.Ident[1:])
+			}
+		}
+	}
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root)
+		}
+	}
+
+	return paths
+}
+
+// setMissing sets the field at a path to an empty string when it is missing,
+// adding the objects leading to it. Paths through values that are not
+// objects are left alone.
+func setMissing(fields map[string]interface{}, path []string) {
+	last := len(path) - 1
+
+	for _, key := range path[:last] {
+		value, ok := fields[key]
+		if !ok {
+			value = make(map[string]interface{})
+			fields[key] = value
+		}
+
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		fields = nested
+	}
+
+	if _, ok := fields[path[last]]; !ok {
+		fields[path[last]] = ""
+	}
+}
@@ -0,0 +1,274 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+const outputInput = `{"level":"warning","ts":1609459200,"msg":"slow request","http":{"status":500},"path":"/a b","expires":1609459300}`
+
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected OutputFormat
+		wantErr  bool
+	}{
+		{"", OutputText, false},
+		{"text", OutputText, false},
+		{"logfmt", OutputLogfmt, false},
+		{"JSON", OutputJSON, false},
+		{"csv", OutputCSV, false},
+		{"tsv", OutputTSV, false},
+		{"template", OutputTemplate, false},
+		{"table", OutputTable, false},
+		{"yaml", OutputText, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOutputFormat(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOutputFormat(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			if got != tt.expected {
+				t.Errorf("ParseOutputFormat(%q) = %v, want %v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatterOutputFormats(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{.Time}} [{{.Level}}] {{.Message}} {{.Fields.http.status}}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error: %v", err)
+	}
+
+	convert := Options{ConvertTimestamps: true, TimestampFields: []string{"expires"}}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "logfmt",
+			opts:     Options{Output: OutputLogfmt},
+			expected: `time=2021-01-01T00:00:00Z level=warn msg="slow request" expires=1.6094593e+09 http.status=500 path="/a b"`,
+		},
+		{
+			name:     "json with timestamp conversion",
+			opts:     Options{Output: OutputJSON, ConvertTimestamps: convert.ConvertTimestamps, TimestampFields: convert.TimestampFields},
+			expected: `{"time":"2021-01-01T00:00:00Z","level":"warn","message":"slow request","expires":"2021-01-01T00:01:40Z","http":{"status":500},"path":"/a b"}`,
+		},
+		{
+			name:     "csv default columns",
+			opts:     Options{Output: OutputCSV},
+			expected: `2021-01-01T00:00:00Z,warn,slow request`,
+		},
+		{
+			name:     "tsv chosen columns",
+			opts:     Options{Output: OutputTSV, Columns: []string{"http.status", "msg", "missing"}},
+			expected: "500\tslow request\t",
+		},
+		{
+			name:     "template",
+			opts:     Options{Output: OutputTemplate, Template: tmpl},
+			expected: `2021-01-01T00:00:00Z [WARN] slow request 500`,
+		},
+	}
+
+	// Epoch times are shown in the local time zone
+	oldLocal := time.Local
+	time.Local = time.UTC
+
+	defer func() { time.Local = oldLocal }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(tt.opts).Format(outputInput)
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Format() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatterJSONFieldSelection(t *testing.T) {
+	input := `{"msg":"request","http":{"method":"GET","status":500},"user":"alice","secret":"x"}`
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "all fields",
+			opts:     Options{Output: OutputJSON},
+			expected: `{"message":"request","http":{"method":"GET","status":500},"secret":"x","user":"alice"}`,
+		},
+		{
+			name:     "chosen fields",
+			opts:     Options{Output: OutputJSON, ShowFields: []string{"user", "http.status"}},
+			expected: `{"message":"request","http":{"status":500},"user":"alice"}`,
+		},
+		{
+			name:     "chosen object",
+			opts:     Options{Output: OutputJSON, ShowFields: []string{"http"}},
+			expected: `{"message":"request","http":{"method":"GET","status":500}}`,
+		},
+		{
+			name:     "hidden fields",
+			opts:     Options{Output: OutputJSON, HideFields: []string{"secret", "http.method"}},
+			expected: `{"message":"request","http":{"status":500},"user":"alice"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(tt.opts).Format(input)
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Format() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatterStructuredKeyCollisions(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		input    string
+		expected string
+	}{
+		{
+			name:     "logfmt",
+			opts:     Options{Output: OutputLogfmt},
+			input:    `{"message":"a","msg":"b","level":"info","severity":"debug"}`,
+			expected: `level=info msg=a fields.msg=b severity=debug`,
+		},
+		{
+			name:     "json",
+			opts:     Options{Output: OutputJSON, Fields: FieldMapping{Time: []string{"ts"}}},
+			input:    `{"ts":"2021-01-01T00:00:00Z","time":"yesterday","msg":"a","message":"b"}`,
+			expected: `{"time":"2021-01-01T00:00:00Z","message":"b","msg":"a","fields.time":"yesterday"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(tt.opts).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Format() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatterHeaderAndUnparsed(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       Options
+		header     string
+		hasHeader  bool
+		unparsed   string
+		unparsedIn string
+	}{
+		{"text", Options{}, "", false, "not json", "not json"},
+		{"json", Options{Output: OutputJSON}, "", false, `{"message":"not <json>"}`, "not <json>"},
+		{"logfmt", Options{Output: OutputLogfmt}, "", false, `msg="not json"`, "not json"},
+		{"csv", Options{Output: OutputCSV, Columns: []string{"level", "message"}}, "level,message", true, `,"not, json"`, "not, json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewFormatter(tt.opts)
+
+			header, ok := formatter.Header()
+			if ok != tt.hasHeader || header != tt.header {
+				t.Errorf("Header() = %q, %v, want %q, %v", header, ok, tt.header, tt.hasHeader)
+			}
+
+			unparsed, err := formatter.FormatUnparsed(tt.unparsedIn)
+			if err != nil {
+				t.Fatalf("FormatUnparsed() error: %v", err)
+			}
+
+			if unparsed != tt.unparsed {
+				t.Errorf("FormatUnparsed() = %q, want %q", unparsed, tt.unparsed)
+			}
+		})
+	}
+}
+
+func TestLogfmtValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"", `""`},
+		{"two words", `"two words"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"a=b", `"a=b"`},
+		{"line\nbreak", `"line\nbreak"`},
+	}
+
+	for _, tt := range tests {
+		if got := logfmtValue(tt.value); got != tt.expected {
+			t.Errorf("logfmtValue(%q) = %s, want %s", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatterTemplateMissingFields(t *testing.T) {
+	tests := []struct {
+		template string
+		input    string
+		expected string
+	}{
+		{
+			template: `{{.Time}} [{{.Level}}] {{.Message}} {{.Fields.caller}}`,
+			input:    `{"time":"2021-01-01T00:00:00Z","level":"info","msg":"no caller"}`,
+			expected: `2021-01-01T00:00:00Z [INFO] no caller `,
+		},
+		{
+			template: `{{.Message}} {{.Fields.caller}}`,
+			input:    `{"msg":"with caller","caller":"main.go:12"}`,
+			expected: `with caller main.go:12`,
+		},
+		{
+			template: `{{.Message}}|{{.Fields.http.status}}|{{if .Fields.user}}{{.Fields.user}}{{else}}anonymous{{end}}`,
+			input:    `{"msg":"nested","http":{}}`,
+			expected: `nested||anonymous`,
+		},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := ParseTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("ParseTemplate(%q) error: %v", tt.template, err)
+		}
+
+		result, err := NewFormatter(Options{Output: OutputTemplate, Template: tmpl}).Format(tt.input)
+		if err != nil {
+			t.Fatalf("Format() error: %v", err)
+		}
+
+		if result != tt.expected {
+			t.Errorf("Format(%s) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
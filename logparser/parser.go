@@ -3,11 +3,10 @@ package logparser
 import (
 	"encoding/json"
 	"fmt"
-	"sort"
 	"strings"
+	"text/template"
 	"time"
-
-	"github.com/fatih/color"
+	"unicode"
 )
 
 // LogLevel represents the severity level of a log entry
@@ -17,8 +16,12 @@ const (
 	LevelTrace LogLevel = iota
 	LevelDebug
 	LevelInfo
+	LevelNotice
 	LevelWarn
 	LevelError
+	LevelCritical
+	LevelFatal
+	LevelPanic
 )
 
 // String returns the string representation of a log level
@@ -30,10 +33,18 @@ func (l LogLevel) String() string {
 		return "DEBUG"
 	case LevelInfo:
 		return "INFO"
+	case LevelNotice:
+		return "NOTICE"
 	case LevelWarn:
 		return "WARN"
 	case LevelError:
 		return "ERROR"
+	case LevelCritical:
+		return "CRITICAL"
+	case LevelFatal:
+		return "FATAL"
+	case LevelPanic:
+		return "PANIC"
 	default:
 		return "UNKNOWN"
 	}
@@ -49,47 +60,44 @@ type LogEntry struct {
 
 // ShouldShowLogLevel determines if a log entry should be shown based on minimum level
 func ShouldShowLogLevel(jsonLine, minLevelStr string) (bool, error) {
-	var rawLog map[string]interface{}
-	if err := json.Unmarshal([]byte(jsonLine), &rawLog); err != nil {
-		return true, nil // If we can't parse JSON, show the line
-	}
-
-	// Extract level from the log entry
-	levelInterface, exists := rawLog["level"]
-	if !exists {
-		return true, nil // If no level field, show the line
-	}
+	return NewFormatter(Options{}).ShouldShow(jsonLine, minLevelStr)
+}
 
-	levelStr, ok := levelInterface.(string)
-	if !ok {
-		return true, nil // If level is not a string, show the line
+// parseLogLevel converts a string to a LogLevel, handling common aliases
+func parseLogLevel(levelStr string) LogLevel {
+	if level, ok := lookupLogLevel(levelStr); ok {
+		return level
 	}
 
-	logLevel := parseLogLevel(levelStr)
-	minLevel := parseLogLevel(minLevelStr)
-
-	// Show if log level is >= minimum level
-	return logLevel >= minLevel, nil
+	// If we don't recognize the level, treat it as INFO
+	return LevelInfo
 }
 
-// parseLogLevel converts a string to a LogLevel, handling common aliases
-func parseLogLevel(levelStr string) LogLevel {
+// lookupLogLevel converts a string to a LogLevel, reporting whether it was recognized
+func lookupLogLevel(levelStr string) (LogLevel, bool) {
 	levelStr = strings.ToUpper(strings.TrimSpace(levelStr))
 
 	switch levelStr {
 	case "TRACE", "TRC":
-		return LevelTrace
+		return LevelTrace, true
 	case "DEBUG", "DBG":
-		return LevelDebug
+		return LevelDebug, true
 	case "INFO", "INF":
-		return LevelInfo
+		return LevelInfo, true
+	case "NOTICE", "NTC":
+		return LevelNotice, true
 	case "WARN", "WARNING", "WRN":
-		return LevelWarn
-	case "ERROR", "ERR", "FATAL", "CRIT", "CRITICAL":
-		return LevelError
+		return LevelWarn, true
+	case "ERROR", "ERR":
+		return LevelError, true
+	case "CRITICAL", "CRIT", "CRT":
+		return LevelCritical, true
+	case "FATAL", "FTL", "ALERT":
+		return LevelFatal, true
+	case "PANIC", "PNC", "EMERG", "EMERGENCY":
+		return LevelPanic, true
 	default:
-		// If we don't recognize the level, treat it as INFO
-		return LevelInfo
+		return LevelInfo, false
 	}
 }
 
@@ -105,190 +113,359 @@ func ParseAndFormatWithColors(jsonLine string, customColors map[string]string) (
 
 // ParseAndFormatWithOptions parses a JSON log line with full configuration options
 func ParseAndFormatWithOptions(jsonLine string, customColors map[string]string, convertTimestamps bool, timestampFields []string) (string, error) {
+	return NewFormatter(Options{
+		CustomColors:      customColors,
+		ConvertTimestamps: convertTimestamps,
+		TimestampFields:   timestampFields,
+	}).Format(jsonLine)
+}
+
+// Options configures how a Formatter parses and formats log lines
+type Options struct {
+	// CustomColors maps words to the style spec they are shown in, see
+	// ParseStyle. Highlights take priority over them, and longer words over
+	// shorter ones.
+	CustomColors map[string]string
+	// Highlights color the parts of messages and field values matching them,
+	// with later rules taking priority where matches overlap
+	Highlights []HighlightRule
+	// FieldColors color the values of single fields, taking priority over
+	// the highlights; later rules win when several match
+	FieldColors []FieldColorRule
+	// HashFields lists fields whose values are each given a color of their
+	// own from the theme's palette, so one request or pod can be followed
+	// through interleaved logs
+	HashFields        []string
+	ConvertTimestamps bool
+	TimestampFields   []string
+	// AutoTimestamps also converts fields whose key looks like a timestamp
+	// and whose value is a plausible one, unless TimestampExclude lists them
+	AutoTimestamps   bool
+	TimestampExclude []string
+	// Fields selects the keys holding the level, time and message. Empty
+	// key lists fall back to DefaultFieldMapping.
+	Fields FieldMapping
+	// LevelScheme selects how numeric levels are decoded
+	LevelScheme LevelScheme
+	// FlattenDepth limits how many levels of nested objects are flattened
+	// into dotted keys; 0 means no limit
+	FlattenDepth int
+	// Expand shows objects, nested arrays and multi-line strings on indented
+	// lines below the entry instead of inline
+	Expand bool
+	// ExpandFields lists fields that are always shown below the entry
+	ExpandFields []string
+	// ModulePaths marks stack trace frames from the user's own code, which
+	// are highlighted
+	ModulePaths []string
+	// Output selects the output format
+	Output OutputFormat
+	// Columns lists the columns of the CSV and TSV formats; empty means
+	// DefaultColumns
+	Columns []string
+	// Template is executed for each entry by the template format
+	Template *template.Template
+	// ShowFields limits the fields shown by the text and logfmt formats to
+	// these keys, in this order. The table format shows them as columns.
+	ShowFields []string
+	// HideFields lists fields that are never shown
+	HideFields []string
+	// FieldsFirst puts the fields before the message in the text and table
+	// formats
+	FieldsFirst bool
+	// Width is the width of the terminal; the table format fits its rows to
+	// it. Zero means the width is unknown and rows are not shortened.
+	Width int
+	// Wrap makes the table format wrap long rows onto indented continuation
+	// lines instead of truncating them
+	Wrap bool
+	// ColumnWidth is the width of the field columns in the table format;
+	// zero means DefaultColumnWidth
+	ColumnWidth int
+	// Theme styles the human-readable layouts; nil means the dark theme
+	Theme *Theme
+	// Color controls whether the human-readable layouts are colored. Callers
+	// resolve ColorAuto to ColorAlways or ColorNever; left as it is, nothing is
+	// colored.
+	Color ColorMode
+	// TimeFormat controls how times are shown by the human-readable layouts
+	TimeFormat TimeFormat
+	// Location is the time zone times are shown in; nil shows them as
+	// logged, with Unix timestamps in local time
+	Location *time.Location
+	// TimeLayouts are Go layouts tried before the built-in ones when parsing
+	// time strings
+	TimeLayouts []string
+	// PreviousTime tracks the previous entry for the delta time format; nil
+	// gives the formatter one of its own
+	PreviousTime *PreviousTime
+}
+
+// Formatter parses and formats log lines using a fixed set of options
+type Formatter struct {
+	opts        Options
+	theme       *Theme
+	highlights  []HighlightRule
+	fieldColors []FieldColorRule
+	previous    *PreviousTime
+	// templateFields are the paths of the fields the Template reads
+	templateFields [][]string
+	// now is the reference of relative times
+	now func() time.Time
+}
+
+// NewFormatter creates a new formatter
+func NewFormatter(opts Options) *Formatter {
+	opts.Fields = opts.Fields.withDefaults()
+
+	theme := opts.Theme
+	if theme == nil {
+		theme = defaultTheme
+	}
+
+	highlights := append(colorRules(opts.CustomColors), opts.Highlights...)
+	fieldColors := opts.FieldColors
+
+	// Without color every role is plain and nothing is highlighted
+	if !opts.Color.Enabled() {
+		theme = plainTheme
+		highlights = nil
+		fieldColors = nil
+	}
+
+	previous := opts.PreviousTime
+	if previous == nil {
+		previous = &PreviousTime{}
+	}
+
+	return &Formatter{
+		opts:           opts,
+		theme:          theme,
+		highlights:     highlights,
+		fieldColors:    fieldColors,
+		previous:       previous,
+		now:            time.Now,
+		templateFields: templateFieldPaths(opts.Template),
+	}
+}
+
+// Parse decodes a JSON log line into a LogEntry using the field mapping
+func (f *Formatter) Parse(jsonLine string) (LogEntry, error) {
 	var rawLog map[string]interface{}
 	if err := json.Unmarshal([]byte(jsonLine), &rawLog); err != nil {
-		return "", fmt.Errorf("failed to parse JSON: %w", err)
+		return LogEntry{}, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
 	entry := LogEntry{
 		Other: make(map[string]interface{}),
 	}
 
-	// Extract known fields
 	for key, value := range rawLog {
-		switch key {
-		case "level":
-			if str, ok := value.(string); ok {
-				entry.Level = str
-			}
-		case "time":
-			entry.Time = value
-		case "message":
-			if str, ok := value.(string); ok {
-				entry.Message = str
-			}
-		default:
-			entry.Other[key] = value
+		entry.Other[key] = value
+	}
+
+	// Extract known fields
+	if key, value, ok := lookupField(rawLog, f.opts.Fields.Level, f.isLevelValue); ok {
+		if name, decoded := levelName(value, f.opts.LevelScheme); decoded {
+			entry.Level = name
+			deletePath(entry.Other, key)
 		}
 	}
 
-	return formatEntryWithOptions(entry, customColors, convertTimestamps, timestampFields), nil
+	if key, value, ok := lookupField(rawLog, f.opts.Fields.Time, anyValue); ok {
+		entry.Time = value
+		deletePath(entry.Other, key)
+	}
+
+	if key, value, ok := lookupField(rawLog, f.opts.Fields.Message, isString); ok {
+		entry.Message = value.(string)
+		deletePath(entry.Other, key)
+	}
+
+	return entry, nil
+}
+
+// Format parses a JSON log line and returns it in the configured output
+// format, by default a colored human-readable string
+func (f *Formatter) Format(jsonLine string) (string, error) {
+	entry, err := f.Parse(jsonLine)
+	if err != nil {
+		return "", err
+	}
+
+	f.hideFields(entry.Other)
+
+	switch f.opts.Output {
+	case OutputText:
+		return f.formatEntry(entry), nil
+	case OutputTable:
+		return f.formatTable(entry), nil
+	default:
+		return f.formatStructured(entry)
+	}
+}
+
+// EntryTime returns the parsed timestamp of a JSON log line
+func (f *Formatter) EntryTime(jsonLine string) (time.Time, bool) {
+	entry, err := f.Parse(jsonLine)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return f.parseTime(entry.Time)
+}
+
+// ShouldShow determines if a log entry should be shown based on minimum level
+func (f *Formatter) ShouldShow(jsonLine, minLevelStr string) (bool, error) {
+	var rawLog map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonLine), &rawLog); err != nil {
+		return true, nil // If we can't parse JSON, show the line
+	}
+
+	// Extract level from the log entry
+	_, levelInterface, exists := lookupField(rawLog, f.opts.Fields.Level, f.isLevelValue)
+	if !exists {
+		return true, nil // If no level field, show the line
+	}
+
+	levelStr, ok := levelName(levelInterface, f.opts.LevelScheme)
+	if !ok {
+		return true, nil // If level can't be decoded, show the line
+	}
+
+	logLevel := parseLogLevel(levelStr)
+	minLevel := parseLogLevel(minLevelStr)
+
+	// Show if log level is >= minimum level
+	return logLevel >= minLevel, nil
+}
+
+// entryBody holds the colored message, fields and expanded blocks of an entry
+// in the human-readable layouts
+type entryBody struct {
+	message  string
+	fields   []string
+	expanded []string
 }
 
-// formatEntryWithOptions formats a LogEntry with full configuration options
-func formatEntryWithOptions(entry LogEntry, customColors map[string]string, convertTimestamps bool, timestampFields []string) string {
+// formatEntry formats a LogEntry using the formatter's options. Expanded
+// fields are removed from entry.Other.
+func (f *Formatter) formatEntry(entry LogEntry) string {
 	var parts []string
 
 	// Format timestamp
-	timeStr := formatTime(entry.Time)
+	timeStr := f.formatEntryTime(entry.Time)
 	if timeStr != "" {
-		parts = append(parts, color.CyanString(timeStr))
+		parts = append(parts, f.theme.sprint("time", timeStr))
 	}
 
 	// Format level with appropriate color
 	if entry.Level != "" {
-		levelStr := formatLevel(entry.Level)
+		levelStr := f.formatLevel(entry.Level)
 		parts = append(parts, levelStr)
 	}
 
-	// Add message with custom coloring
-	if entry.Message != "" {
-		messageStr := applyCustomColors(entry.Message, customColors)
-		parts = append(parts, messageStr)
+	body := f.formatBody(entry)
+	if line := f.joinBody(body); line != "" {
+		parts = append(parts, line)
 	}
 
-	// Add other fields as key=value pairs
-	var (
-		otherParts []string
-		keys       []string
-	)
-	for key := range entry.Other {
-		keys = append(keys, key)
+	lines := append([]string{strings.Join(parts, " ")}, body.expanded...)
+
+	return strings.Join(lines, "\n")
+}
+
+// formatBody formats the message and fields of an entry, moving the fields
+// to expand and any stack traces into the expanded blocks
+func (f *Formatter) formatBody(entry LogEntry) entryBody {
+	var body entryBody
+
+	// Add message with custom coloring
+	if entry.Message != "" {
+		body.message = f.formatMessage(entry.Message)
 	}
 
-	sort.Strings(keys) // Sort for consistent output
+	// Fields to expand are shown on their own lines below the entry
+	var expanded []expandedField
 
-	for _, key := range keys {
-		value := entry.Other[key]
-		keyStr := color.MagentaString(key)
+	for _, field := range takeExpandedFields(entry.Other, f.opts.ExpandFields, f.opts.Expand) {
+		if f.isSelected(field.key) {
+			expanded = append(expanded, field)
+		}
+	}
 
-		// Check if this field should be converted to a timestamp
-		var convertedValue string
-		if convertTimestamps {
-			convertedValue = convertTimestampFieldWithConfig(key, value, timestampFields)
-		} else {
-			convertedValue = fmt.Sprintf("%v", value)
+	// Add other fields as key=value pairs, with nested objects flattened
+	// into dotted keys
+	fields := Flatten(entry.Other, f.opts.FlattenDepth)
+	for _, key := range f.selectKeys(fields) {
+		value := fields[key]
+
+		// Stack traces are always shown frame by frame below the entry
+		if text, ok := value.(string); ok {
+			if _, language := findTrace(text); language != traceNone {
+				expanded = append(expanded, expandedField{key: key, value: text})
+				continue
+			}
 		}
 
-		valueStr := applyCustomColors(color.YellowString(convertedValue), customColors)
-		otherParts = append(otherParts, fmt.Sprintf("%s=%s", keyStr, valueStr))
+		keyStr := f.theme.sprint("key", key)
+		valueStr := f.fieldValue(key, value)
+		body.fields = append(body.fields, fmt.Sprintf("%s=%s", keyStr, valueStr))
 	}
 
-	if len(otherParts) > 0 {
-		parts = append(parts, strings.Join(otherParts, " "))
+	for _, field := range expanded {
+		body.expanded = append(body.expanded, f.formatExpanded(field))
 	}
 
-	return strings.Join(parts, " ")
+	return body
 }
 
-// formatTime converts various time formats to a readable string
-func formatTime(timeVal interface{}) string {
-	if timeVal == nil {
-		return ""
-	}
-
-	switch t := timeVal.(type) {
-	case float64:
-		// Assume milliseconds if > 1e10, otherwise seconds
-		if t > 1e10 {
-			return time.Unix(0, int64(t)*int64(time.Millisecond)).Format("2006-01-02 15:04:05")
-		}
+// joinBody joins the message and fields of an entry into one line
+func (f *Formatter) joinBody(body entryBody) string {
+	var parts []string
 
-		return time.Unix(int64(t), 0).Format("2006-01-02 15:04:05")
-	case int64:
-		// Assume milliseconds if > 1e10, otherwise seconds
-		if t > 1e10 {
-			return time.Unix(0, t*int64(time.Millisecond)).Format("2006-01-02 15:04:05")
-		}
+	// The fields go before the message with FieldsFirst
+	if len(body.fields) > 0 && f.opts.FieldsFirst {
+		parts = append(parts, strings.Join(body.fields, " "))
+	}
 
-		return time.Unix(t, 0).Format("2006-01-02 15:04:05")
-	case string:
-		// Try to parse as RFC3339 or other common formats
-		if parsed, err := time.Parse(time.RFC3339, t); err == nil {
-			return parsed.Format("2006-01-02 15:04:05")
-		}
+	if body.message != "" {
+		parts = append(parts, body.message)
+	}
 
-		if parsed, err := time.Parse("2006-01-02T15:04:05", t); err == nil {
-			return parsed.Format("2006-01-02 15:04:05")
-		}
-		// If parsing fails, return as-is
-		return t
-	default:
-		return fmt.Sprintf("%v", timeVal)
+	if len(body.fields) > 0 && !f.opts.FieldsFirst {
+		parts = append(parts, strings.Join(body.fields, " "))
 	}
+
+	return strings.Join(parts, " ")
 }
 
-// formatLevel returns a colored level string
-func formatLevel(level string) string {
-	level = strings.ToUpper(level)
-	switch level {
-	case "ERROR", "ERR":
-		return color.RedString(level)
-	case "WARN", "WARNING":
-		return color.YellowString(level)
-	case "INFO":
-		return color.GreenString(level)
-	case "DEBUG":
-		return color.BlueString(level)
-	case "TRACE":
-		return color.MagentaString(level)
-	default:
-		return color.WhiteString(level)
+// displayValue renders a field value, converting it when it is one of the
+// timestamp fields
+func (f *Formatter) displayValue(key string, value interface{}) string {
+	if f.opts.ConvertTimestamps && f.isTimestamp(key, value) {
+		return convertTimestampValue(value, f.formatTimeValue)
 	}
+
+	return formatValue(value)
 }
 
-// applyCustomColors applies custom color rules to a string
-func applyCustomColors(text string, customColors map[string]string) string {
-	if len(customColors) == 0 {
-		return color.WhiteString(text)
+// formatTime converts various time formats to a readable string
+func formatTime(timeVal interface{}) string {
+	if timeVal == nil {
+		return ""
 	}
 
-	result := text
-	for word, colorName := range customColors {
-		if strings.Contains(result, word) {
-			coloredWord := getColorFunc(colorName)(word)
-			result = strings.ReplaceAll(result, word, coloredWord)
-		}
+	if parsed, ok := ParseTime(timeVal); ok {
+		return parsed.Format(DefaultTimeLayout)
 	}
 
-	// If no custom colors were applied, use default white
-	if result == text {
-		result = color.WhiteString(text)
+	if t, ok := timeVal.(string); ok {
+		// If parsing fails, return as-is
+		return t
 	}
 
-	return result
-}
-
-// getColorFunc returns the appropriate color function based on color name
-func getColorFunc(colorName string) func(string) string {
-	switch strings.ToLower(colorName) {
-	case "red":
-		return func(s string) string { return color.RedString(s) }
-	case "green":
-		return func(s string) string { return color.GreenString(s) }
-	case "yellow":
-		return func(s string) string { return color.YellowString(s) }
-	case "blue":
-		return func(s string) string { return color.BlueString(s) }
-	case "magenta":
-		return func(s string) string { return color.MagentaString(s) }
-	case "cyan":
-		return func(s string) string { return color.CyanString(s) }
-	case "white":
-		return func(s string) string { return color.WhiteString(s) }
-	default:
-		return func(s string) string { return color.WhiteString(s) }
-	}
+	return fmt.Sprintf("%v", timeVal)
 }
 
 // isTimestampField checks if a field name suggests it contains a timestamp
@@ -324,6 +501,76 @@ func isTimestampField(fieldName string) bool {
 	return false
 }
 
+// Epoch timestamps are only converted automatically when they fall between
+// these years, so durations and counters are left alone
+const (
+	minAutoTimestampYear = 1990
+	maxAutoTimestampYear = 2100
+)
+
+// isTimestamp reports whether a field is converted as a timestamp: it is one
+// of the TimestampFields or, with AutoTimestamps, it is not excluded, its key
+// looks like a timestamp and its value is a plausible one
+func (f *Formatter) isTimestamp(key string, value interface{}) bool {
+	for _, field := range f.opts.TimestampFields {
+		if strings.EqualFold(key, field) {
+			return true
+		}
+	}
+
+	if !f.opts.AutoTimestamps {
+		return false
+	}
+
+	for _, excluded := range f.opts.TimestampExclude {
+		if matchesKey(strings.ToLower(key), strings.ToLower(excluded)) {
+			return false
+		}
+	}
+
+	return isTimestampKey(key) && f.isPlausibleTimestamp(value)
+}
+
+// isTimestampKey applies isTimestampField to the last part of a dotted key,
+// with camelCase names such as createdAt read as created_at
+func isTimestampKey(key string) bool {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+
+	var b strings.Builder
+
+	for i, r := range key {
+		if i > 0 && unicode.IsUpper(r) {
+			previous := rune(key[i-1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) {
+				b.WriteByte('_')
+			}
+		}
+
+		b.WriteRune(r)
+	}
+
+	return isTimestampField(key) || isTimestampField(b.String())
+}
+
+// isPlausibleTimestamp reports whether a value is a date string or an epoch
+// timestamp in a plausible range
+func (f *Formatter) isPlausibleTimestamp(value interface{}) bool {
+	parsed, ok := f.parseTime(value)
+	if !ok {
+		return false
+	}
+
+	if text, ok := value.(string); ok && !isEpochString(strings.TrimSpace(text)) {
+		return true
+	}
+
+	year := parsed.Year()
+
+	return year >= minAutoTimestampYear && year <= maxAutoTimestampYear
+}
+
 // convertTimestampField converts a field value to human-readable date if it looks like a timestamp
 func convertTimestampField(fieldName string, value interface{}) string {
 	return convertTimestampFieldWithConfig(fieldName, value, nil)
@@ -331,6 +578,11 @@ func convertTimestampField(fieldName string, value interface{}) string {
 
 // convertTimestampFieldWithConfig converts a field value to human-readable date with custom field configuration
 func convertTimestampFieldWithConfig(fieldName string, value interface{}, customFields []string) string {
+	return convertTimestampFieldWith(fieldName, value, customFields, formatTime)
+}
+
+// convertTimestampFieldWith converts a field value with a time formatting function
+func convertTimestampFieldWith(fieldName string, value interface{}, customFields []string, format func(interface{}) string) string {
 	// Check if this field should be converted - only if it's in the custom fields list
 	shouldConvert := false
 
@@ -342,11 +594,16 @@ func convertTimestampFieldWithConfig(fieldName string, value interface{}, custom
 	}
 
 	if !shouldConvert {
-		return fmt.Sprintf("%v", value)
+		return formatValue(value)
 	}
 
+	return convertTimestampValue(value, format)
+}
+
+// convertTimestampValue shows a timestamp value both formatted and as logged
+func convertTimestampValue(value interface{}, format func(interface{}) string) string {
 	// Try to convert the value to a timestamp
-	converted := formatTime(value)
+	converted := format(value)
 	originalStr := fmt.Sprintf("%v", value)
 
 	if converted != "" && converted != originalStr {
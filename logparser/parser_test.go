@@ -148,12 +148,16 @@ func TestFormatLevel(t *testing.T) {
 		{"info", "info", "INFO"},
 		{"warn", "warn", "WARN"},
 		{"error", "error", "ERROR"},
+		{"notice", "notice", "NOTICE"},
+		{"critical", "crit", "CRIT"},
+		{"fatal", "fatal", "FATAL"},
+		{"panic", "panic", "PANIC"},
 		{"unknown", "custom", "CUSTOM"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := formatLevel(tt.input)
+			result := defaultTheme.formatLevel(tt.input)
 			// Just check that the level text is present (ignoring ANSI color codes)
 			if !strings.Contains(result, tt.want) {
 				t.Errorf("formatLevel() = %q, should contain %q", result, tt.want)
@@ -246,7 +250,7 @@ func TestParseAndFormatWithColors(t *testing.T) {
 	}
 }
 
-func TestGetColorFunc(t *testing.T) {
+func TestCustomColorRules(t *testing.T) {
 	tests := []struct {
 		colorName string
 		testWord  string
@@ -263,12 +267,12 @@ func TestGetColorFunc(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.colorName, func(t *testing.T) {
-			colorFunc := getColorFunc(tt.colorName)
-			result := colorFunc(tt.testWord)
+			formatter := NewFormatter(Options{CustomColors: map[string]string{tt.testWord: tt.colorName}, Color: ColorAlways})
+			result := formatter.highlight("message", tt.testWord)
 
-			// Just check that the function returns something and includes the original word
-			if !strings.Contains(result, tt.testWord) {
-				t.Errorf("getColorFunc(%q)(%q) should contain the original word, got: %s", tt.colorName, tt.testWord, result)
+			// Check that the word is colored, falling back to white for unknown colors
+			if !strings.Contains(result, tt.testWord) || !strings.HasPrefix(result, "\x1b[") {
+				t.Errorf("color rule %q for %q should color the word, got: %q", tt.colorName, tt.testWord, result)
 			}
 		})
 	}
@@ -341,6 +345,30 @@ func TestShouldShowLogLevel(t *testing.T) {
 			minLevel:   "error",
 			shouldShow: true,
 		},
+		{
+			name:       "error level with fatal filter",
+			jsonLine:   `{"level":"error","message":"Error message"}`,
+			minLevel:   "fatal",
+			shouldShow: false,
+		},
+		{
+			name:       "panic level with fatal filter",
+			jsonLine:   `{"level":"panic","message":"Panic"}`,
+			minLevel:   "fatal",
+			shouldShow: true,
+		},
+		{
+			name:       "notice level with warning filter",
+			jsonLine:   `{"level":"notice","message":"Notice"}`,
+			minLevel:   "warning",
+			shouldShow: false,
+		},
+		{
+			name:       "notice level with info filter",
+			jsonLine:   `{"level":"notice","message":"Notice"}`,
+			minLevel:   "info",
+			shouldShow: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -380,11 +408,17 @@ func TestParseLogLevel(t *testing.T) {
 		{"error", LevelError},
 		{"ERROR", LevelError},
 		{"ERR", LevelError},
-		{"fatal", LevelError},
-		{"FATAL", LevelError},
-		{"critical", LevelError},
-		{"CRITICAL", LevelError},
-		{"CRIT", LevelError},
+		{"notice", LevelNotice},
+		{"NOTICE", LevelNotice},
+		{"critical", LevelCritical},
+		{"CRITICAL", LevelCritical},
+		{"CRIT", LevelCritical},
+		{"fatal", LevelFatal},
+		{"FATAL", LevelFatal},
+		{"alert", LevelFatal},
+		{"panic", LevelPanic},
+		{"PANIC", LevelPanic},
+		{"emerg", LevelPanic},
 		{"unknown", LevelInfo}, // Default to INFO for unknown levels
 		{"", LevelInfo},        // Default to INFO for empty string
 		{" WARN ", LevelWarn},  // Should handle whitespace
@@ -408,8 +442,12 @@ func TestLogLevelString(t *testing.T) {
 		{LevelTrace, "TRACE"},
 		{LevelDebug, "DEBUG"},
 		{LevelInfo, "INFO"},
+		{LevelNotice, "NOTICE"},
 		{LevelWarn, "WARN"},
 		{LevelError, "ERROR"},
+		{LevelCritical, "CRITICAL"},
+		{LevelFatal, "FATAL"},
+		{LevelPanic, "PANIC"},
 		{LogLevel(999), "UNKNOWN"}, // Invalid level
 	}
 
@@ -433,13 +471,29 @@ func TestLevelHierarchy(t *testing.T) {
 		t.Error("DEBUG should be < INFO")
 	}
 
-	if LevelInfo >= LevelWarn {
-		t.Error("INFO should be < WARN")
+	if LevelInfo >= LevelNotice {
+		t.Error("INFO should be < NOTICE")
+	}
+
+	if LevelNotice >= LevelWarn {
+		t.Error("NOTICE should be < WARN")
 	}
 
 	if LevelWarn >= LevelError {
 		t.Error("WARN should be < ERROR")
 	}
+
+	if LevelError >= LevelCritical {
+		t.Error("ERROR should be < CRITICAL")
+	}
+
+	if LevelCritical >= LevelFatal {
+		t.Error("CRITICAL should be < FATAL")
+	}
+
+	if LevelFatal >= LevelPanic {
+		t.Error("FATAL should be < PANIC")
+	}
 }
 
 func TestIsTimestampField(t *testing.T) {
@@ -658,3 +712,66 @@ func TestTimestampFieldConversionInLogs(t *testing.T) {
 		})
 	}
 }
+
+func TestAutoTimestamps(t *testing.T) {
+	formatter := NewFormatter(Options{
+		ConvertTimestamps: true,
+		AutoTimestamps:    true,
+		TimestampFields:   []string{"deadline"},
+		TimestampExclude:  []string{"since", "audit"},
+		Location:          time.UTC,
+	})
+
+	tests := []struct {
+		key      string
+		value    interface{}
+		expected bool
+	}{
+		{"created", float64(1709294400), true},
+		{"createdAt", float64(1709294400123), true},
+		{"http.updated_at", "1709294400", true},
+		{"expires", "2024-03-02T00:00:00Z", true},
+		{"lastSeen", "Fri, 01 Mar 2024 12:00:00 GMT", true},
+		{"deadline", float64(30), true},
+		{"timeout", float64(30), false},
+		{"uptime", float64(3600), false},
+		{"elapsed_time", "1.5s", false},
+		{"from", "alice@example.com", false},
+		{"message", "2024-03-02T00:00:00Z", false},
+		{"since", float64(1709294400), false},
+		{"audit.created", float64(1709294400), false},
+		{"Since", float64(1709294400), false},
+	}
+
+	for _, tt := range tests {
+		if got := formatter.isTimestamp(tt.key, tt.value); got != tt.expected {
+			t.Errorf("isTimestamp(%q, %v) = %v, want %v", tt.key, tt.value, got, tt.expected)
+		}
+	}
+
+	result, err := formatter.Format(`{"msg":"x","createdAt":1709294400,"timeout":30}`)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+
+	if expected := "x createdAt=2024-03-01 12:00:00 (1.7092944e+09) timeout=30"; result != expected {
+		t.Errorf("Format() = %q, want %q", result, expected)
+	}
+
+	formatter = NewFormatter(Options{ConvertTimestamps: true, AutoTimestamps: true, Output: OutputJSON, Location: time.UTC})
+
+	result, err = formatter.Format(`{"msg":"x","http":{"updated_at":1709294400},"timeout":30}`)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+
+	if expected := `{"message":"x","http":{"updated_at":"2024-03-01T12:00:00Z"},"timeout":30}`; result != expected {
+		t.Errorf("Format() = %q, want %q", result, expected)
+	}
+
+	// Without auto only the listed fields are converted
+	formatter = NewFormatter(Options{ConvertTimestamps: true, TimestampFields: []string{"expires"}})
+	if formatter.isTimestamp("created", float64(1709294400)) {
+		t.Error("isTimestamp() converted an unlisted field without AutoTimestamps")
+	}
+}
@@ -0,0 +1,92 @@
+package logparser
+
+import (
+	"sort"
+	"strings"
+)
+
+// hideFields removes the hidden fields from an entry's other fields. Hidden
+// keys may be dotted paths, and hiding an object hides everything in it.
+func (f *Formatter) hideFields(fields map[string]interface{}) {
+	for _, key := range f.opts.HideFields {
+		deletePath(fields, key)
+	}
+}
+
+// selectKeys returns the keys of flattened fields to show, in display order.
+// Without ShowFields every key is shown in sorted order; otherwise only the
+// keys matching ShowFields are shown, in the order they were chosen.
+func (f *Formatter) selectKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys) // Sort for consistent output
+
+	if len(f.opts.ShowFields) == 0 {
+		return keys
+	}
+
+	var selected []string
+
+	seen := make(map[string]bool)
+
+	for _, selector := range f.opts.ShowFields {
+		for _, key := range keys {
+			if !seen[key] && matchesKey(key, selector) {
+				seen[key] = true
+				selected = append(selected, key)
+			}
+		}
+	}
+
+	return selected
+}
+
+// selectFields returns the fields chosen by ShowFields without flattening
+// them. Objects that are chosen are kept whole, and those that a dotted
+// selector reaches into keep only the chosen fields inside them. prefix is
+// the dotted path of the fields.
+func (f *Formatter) selectFields(fields map[string]interface{}, prefix string) map[string]interface{} {
+	if len(f.opts.ShowFields) == 0 {
+		return fields
+	}
+
+	selected := make(map[string]interface{})
+
+	for key, value := range fields {
+		if f.isSelected(prefix + key) {
+			selected[key] = value
+			continue
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			if inner := f.selectFields(nested, prefix+key+"."); len(inner) > 0 {
+				selected[key] = inner
+			}
+		}
+	}
+
+	return selected
+}
+
+// isSelected reports whether a field is shown by ShowFields
+func (f *Formatter) isSelected(key string) bool {
+	if len(f.opts.ShowFields) == 0 {
+		return true
+	}
+
+	for _, selector := range f.opts.ShowFields {
+		if matchesKey(key, selector) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesKey reports whether a flattened key is selector or nested inside it
+func matchesKey(key, selector string) bool {
+	return key == selector || strings.HasPrefix(key, selector+".")
+}
@@ -0,0 +1,80 @@
+package logparser
+
+import "testing"
+
+func TestFormatterFieldSelection(t *testing.T) {
+	input := `{"level":"info","msg":"hello","caller":"main.go:1","subsystem":"api","program":"glug","user":{"name":"alice","password":"secret"},"token":"abc"}`
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "all fields sorted",
+			opts:     Options{},
+			expected: "INFO hello caller=main.go:1 program=glug subsystem=api token=abc user.name=alice user.password=secret",
+		},
+		{
+			name:     "chosen fields in chosen order",
+			opts:     Options{ShowFields: []string{"subsystem", "caller", "missing"}},
+			expected: "INFO hello subsystem=api caller=main.go:1",
+		},
+		{
+			name:     "choosing an object shows its nested keys",
+			opts:     Options{ShowFields: []string{"user", "caller"}},
+			expected: "INFO hello user.name=alice user.password=secret caller=main.go:1",
+		},
+		{
+			name:     "hidden fields and nested keys",
+			opts:     Options{HideFields: []string{"token", "program", "user.password"}},
+			expected: "INFO hello caller=main.go:1 subsystem=api user.name=alice",
+		},
+		{
+			name:     "hiding wins over choosing",
+			opts:     Options{ShowFields: []string{"user"}, HideFields: []string{"user.password"}},
+			expected: "INFO hello user.name=alice",
+		},
+		{
+			name:     "fields first",
+			opts:     Options{ShowFields: []string{"caller"}, FieldsFirst: true},
+			expected: "INFO caller=main.go:1 hello",
+		},
+		{
+			name:     "logfmt honors selection",
+			opts:     Options{Output: OutputLogfmt, ShowFields: []string{"subsystem"}},
+			expected: "level=info msg=hello subsystem=api",
+		},
+		{
+			name:     "json drops hidden fields",
+			opts:     Options{Output: OutputJSON, HideFields: []string{"user", "token", "program"}},
+			expected: `{"level":"info","message":"hello","caller":"main.go:1","subsystem":"api"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(tt.opts).Format(input)
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Format() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatterSelectionAppliesToExpandedFields(t *testing.T) {
+	input := `{"msg":"boom","stack":"goroutine 1 [running]:\nmain.main()\n\t/app/main.go:1","body":{"a":1}}`
+
+	result, err := NewFormatter(Options{Expand: true, HideFields: []string{"body"}, ShowFields: []string{"body", "other"}}).Format(input)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+
+	if result != "boom" {
+		t.Errorf("Format() = %q, want only the message", result)
+	}
+}
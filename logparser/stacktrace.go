@@ -0,0 +1,205 @@
+package logparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// traceLanguage identifies the format of a stack trace
+type traceLanguage int
+
+const (
+	traceNone traceLanguage = iota
+	traceGo
+	traceJava
+	tracePython
+)
+
+// frameKind classifies a line of a stack trace for coloring
+type frameKind int
+
+const (
+	// frameHeader is a line that is not a frame, such as the panic message
+	frameHeader frameKind = iota
+	// frameOther is a frame from third-party code
+	frameOther
+	// frameOwn is a frame from one of the configured module paths
+	frameOwn
+	// frameStdlib is a frame from the language runtime or standard library
+	frameStdlib
+)
+
+var (
+	goFileLine     = regexp.MustCompile(`(?m)^\t\S+\.go:\d+`)
+	goFuncLine     = regexp.MustCompile(`^(created by [\w./*()\[\]-]+( in goroutine \d+)?|[\w./*()\[\]-]+\(.*\))$`)
+	javaFrameLine  = regexp.MustCompile(`(?m)^\s+at [\w$.<>/]+\(.*\)\s*$`)
+	pythonFileLine = regexp.MustCompile(`(?m)^\s+File "([^"]+)", line \d+`)
+)
+
+// javaStdlibPrefixes are the packages of the Java runtime and JVM languages
+var javaStdlibPrefixes = []string{"java.", "javax.", "jdk.", "sun.", "com.sun.", "kotlin.", "scala."}
+
+// detectTrace reports the language of a stack trace in text, if any
+func detectTrace(text string) traceLanguage {
+	switch {
+	case strings.Contains(text, "Traceback (most recent call last):") || pythonFileLine.MatchString(text):
+		return tracePython
+	case javaFrameLine.MatchString(text):
+		return traceJava
+	case goFileLine.MatchString(text):
+		return traceGo
+	}
+
+	return traceNone
+}
+
+// findTrace returns text as a stack trace, undoing a second level of escaping
+// when the trace was logged with literal \n and \t sequences
+func findTrace(text string) (string, traceLanguage) {
+	if language := detectTrace(text); language != traceNone {
+		return text, language
+	}
+
+	if strings.Contains(text, "\n") || !strings.Contains(text, `\n`) {
+		return "", traceNone
+	}
+
+	unescaped := strings.NewReplacer(`\r\n`, "\n", `\n`, "\n", `\t`, "\t").Replace(text)
+	if language := detectTrace(unescaped); language != traceNone {
+		return unescaped, language
+	}
+
+	return "", traceNone
+}
+
+// formatTrace renders a stack trace one line per row, indenting frames and
+// coloring them by origin: frames under modulePaths are highlighted and
+// runtime or standard library frames are dimmed
+func (f *Formatter) formatTrace(text string, language traceLanguage) []string {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var (
+		lines []string
+		kind  frameKind
+	)
+
+	for _, line := range strings.Split(text, "\n") {
+		kind = classifyTraceLine(line, language, kind, f.opts.ModulePaths)
+		lines = append(lines, f.colorFrame(indentTraceLine(line), kind))
+	}
+
+	return lines
+}
+
+// indentTraceLine replaces leading tabs with two spaces each so frames line
+// up the same way in every terminal
+func indentTraceLine(line string) string {
+	trimmed := strings.TrimLeft(line, "\t")
+	return strings.Repeat("  ", len(line)-len(trimmed)) + trimmed
+}
+
+// classifyTraceLine works out the kind of a trace line. Lines that continue
+// a frame, such as Go file positions and Python source lines, take the kind
+// of the frame before them.
+func classifyTraceLine(line string, language traceLanguage, previous frameKind, modulePaths []string) frameKind {
+	trimmed := strings.TrimSpace(line)
+
+	switch language {
+	case traceGo:
+		if strings.HasPrefix(line, "\t") {
+			return continueFrame(line, previous, modulePaths)
+		}
+
+		if !goFuncLine.MatchString(trimmed) {
+			return frameHeader
+		}
+
+		return frameKindOf(line, isGoStdlib(strings.TrimPrefix(trimmed, "created by ")), modulePaths)
+	case traceJava:
+		if !strings.HasPrefix(trimmed, "at ") {
+			// Indented lines such as "... 12 more" belong to the frames
+			if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+				return frameOther
+			}
+
+			return frameHeader
+		}
+
+		return frameKindOf(line, hasAnyPrefix(strings.TrimPrefix(trimmed, "at "), javaStdlibPrefixes), modulePaths)
+	case tracePython:
+		if match := pythonFileLine.FindStringSubmatch(line); match != nil {
+			path := match[1]
+			stdlib := strings.HasPrefix(path, "<") || (strings.Contains(path, "/lib/python") && !strings.Contains(path, "-packages/"))
+
+			return frameKindOf(line, stdlib, modulePaths)
+		}
+
+		if strings.HasPrefix(line, " ") && previous != frameHeader {
+			return continueFrame(line, previous, modulePaths)
+		}
+	}
+
+	return frameHeader
+}
+
+// continueFrame returns the kind of a line continuing a frame of kind previous
+func continueFrame(line string, previous frameKind, modulePaths []string) frameKind {
+	if previous == frameHeader {
+		return frameKindOf(line, false, modulePaths)
+	}
+
+	return previous
+}
+
+// frameKindOf classifies a frame line, preferring the configured module paths
+func frameKindOf(line string, stdlib bool, modulePaths []string) frameKind {
+	for _, path := range modulePaths {
+		if path != "" && strings.Contains(line, path) {
+			return frameOwn
+		}
+	}
+
+	if stdlib {
+		return frameStdlib
+	}
+
+	return frameOther
+}
+
+// isGoStdlib reports whether a Go function belongs to the standard library:
+// the first element of its import path has no dot and is not main
+func isGoStdlib(function string) bool {
+	first := function
+	if i := strings.Index(first, "/"); i >= 0 {
+		first = first[:i]
+	} else if i := strings.Index(first, "."); i >= 0 {
+		first = first[:i]
+	}
+
+	return first != "main" && !strings.Contains(first, ".")
+}
+
+// hasAnyPrefix reports whether s starts with any of prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// colorFrame colors a trace line according to its kind
+func (f *Formatter) colorFrame(line string, kind frameKind) string {
+	switch kind {
+	case frameHeader:
+		return f.highlight("frame_header", line)
+	case frameOwn:
+		return f.highlight("frame_own", line)
+	case frameStdlib:
+		return f.highlight("frame_stdlib", line)
+	}
+
+	return f.highlight("message", line)
+}
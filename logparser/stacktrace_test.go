@@ -0,0 +1,145 @@
+package logparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	goTrace = "panic: boom\n\ngoroutine 1 [running]:\nmain.main()\n\t/app/main.go:12 +0x1d\n" +
+		"github.com/acme/app/x.Run(0x1)\n\t/app/x/x.go:40 +0x2\n" +
+		"net/http.(*conn).serve(0xc0)\n\t/usr/local/go/src/net/http/server.go:2009 +0x5\n" +
+		"created by net/http.(*Server).Serve in goroutine 1\n\t/usr/local/go/src/net/http/server.go:3086 +0x4"
+	javaTrace = "java.lang.IllegalStateException: bad\n\tat com.acme.Foo.bar(Foo.java:10)\n" +
+		"\tat org.lib.Util.call(Util.java:5)\n\tat java.base/java.lang.Thread.run(Thread.java:833)\n" +
+		"Caused by: java.io.IOException: x\n\t... 5 more"
+	pythonTrace = "Traceback (most recent call last):\n" +
+		"  File \"/usr/lib/python3.11/runpy.py\", line 196, in _run\n    exec(code)\n" +
+		"  File \"/srv/acme/app.py\", line 3, in <module>\n    main()\n" +
+		"  File \"/venv/lib/python3.11/site-packages/lib.py\", line 9, in call\n    raise\n" +
+		"ValueError: nope"
+)
+
+func TestFindTrace(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected traceLanguage
+		trace    string
+	}{
+		{"go", goTrace, traceGo, goTrace},
+		{"java", javaTrace, traceJava, javaTrace},
+		{"python", pythonTrace, tracePython, pythonTrace},
+		{"escaped go", strings.NewReplacer("\n", `\n`, "\t", `\t`).Replace(goTrace), traceGo, goTrace},
+		{"plain text", "line one\nline two", traceNone, ""},
+		{"escaped plain text", `line one\nline two`, traceNone, ""},
+		{"single line", "error at startup", traceNone, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, language := findTrace(tt.text)
+			if language != tt.expected {
+				t.Errorf("findTrace() language = %v, want %v", language, tt.expected)
+			}
+
+			if trace != tt.trace {
+				t.Errorf("findTrace() trace = %q, want %q", trace, tt.trace)
+			}
+		})
+	}
+}
+
+func TestClassifyTraceLines(t *testing.T) {
+	modulePaths := []string{"github.com/acme", "com.acme", "/srv/acme"}
+
+	tests := []struct {
+		name     string
+		trace    string
+		language traceLanguage
+		expected []frameKind
+	}{
+		{
+			name:     "go",
+			trace:    goTrace,
+			language: traceGo,
+			expected: []frameKind{
+				frameHeader, frameHeader, frameHeader,
+				frameOther, frameOther, // main is not the standard library
+				frameOwn, frameOwn,
+				frameStdlib, frameStdlib,
+				frameStdlib, frameStdlib,
+			},
+		},
+		{
+			name:     "java",
+			trace:    javaTrace,
+			language: traceJava,
+			expected: []frameKind{frameHeader, frameOwn, frameOther, frameStdlib, frameHeader, frameOther},
+		},
+		{
+			name:     "python",
+			trace:    pythonTrace,
+			language: tracePython,
+			expected: []frameKind{
+				frameHeader,
+				frameStdlib, frameStdlib,
+				frameOwn, frameOwn,
+				frameOther, frameOther,
+				frameHeader,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				kinds []frameKind
+				kind  frameKind
+			)
+
+			for _, line := range strings.Split(tt.trace, "\n") {
+				kind = classifyTraceLine(line, tt.language, kind, modulePaths)
+				kinds = append(kinds, kind)
+			}
+
+			if !reflect.DeepEqual(kinds, tt.expected) {
+				t.Errorf("classifyTraceLine() kinds = %v, want %v", kinds, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsGoStdlib(t *testing.T) {
+	tests := []struct {
+		function string
+		expected bool
+	}{
+		{"runtime.gopark(0x0)", true},
+		{"net/http.(*conn).serve(0xc0)", true},
+		{"main.main()", false},
+		{"github.com/acme/app.Run()", false},
+		{"golang.org/x/sync/errgroup.(*Group).Go.func1()", false},
+	}
+
+	for _, tt := range tests {
+		if got := isGoStdlib(tt.function); got != tt.expected {
+			t.Errorf("isGoStdlib(%q) = %v, want %v", tt.function, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatterRendersTraces(t *testing.T) {
+	input := `{"level":"error","msg":"crashed","user":"alice","stacktrace":"goroutine 1 [running]:\\nmain.main()\\n\\t/app/main.go:12 +0x1d"}`
+
+	result, err := NewFormatter(Options{}).Format(input)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+
+	expected := "ERROR crashed user=alice\n  stacktrace:\n    goroutine 1 [running]:\n    main.main()\n      /app/main.go:12 +0x1d"
+	if result != expected {
+		t.Errorf("Format() =\n%s\nwant\n%s", result, expected)
+	}
+}
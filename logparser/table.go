@@ -0,0 +1,281 @@
+package logparser
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// DefaultColumnWidth is the width of the field columns in the table format
+	DefaultColumnWidth = 16
+
+	// tableLevelWidth fits the longest canonical level name, CRITICAL
+	tableLevelWidth = 8
+	// minMessageWidth is the least room left for the message when the
+	// columns take up most of the terminal
+	minMessageWidth = 20
+
+	ellipsis = "…"
+	resetSGR = "\x1b[0m"
+)
+
+// formatTable formats an entry as a row of fixed-width columns: the time, the
+// level and each of the ShowFields, followed by the message and, when no
+// fields were chosen, the other fields as key=value pairs. The rest of the
+// row is truncated to fit Width or, with Wrap, wrapped onto continuation
+// lines indented to line up with the message.
+func (f *Formatter) formatTable(entry LogEntry) string {
+	level := normalizeLevel(entry.Level)
+	if runewidth.StringWidth(level) > tableLevelWidth {
+		level = truncateWidth(level, tableLevelWidth)
+	}
+
+	columns := []string{
+		f.theme.sprint("time", fitWidth(f.formatEntryTime(entry.Time), f.opts.TimeFormat.width(f.opts.Location))),
+		padWidth(f.formatLevel(level), tableLevelWidth),
+	}
+
+	columnWidth := f.opts.ColumnWidth
+	if columnWidth <= 0 {
+		columnWidth = DefaultColumnWidth
+	}
+
+	for _, key := range f.opts.ShowFields {
+		v, ok := lookupPath(entry.Other, key)
+		if !ok {
+			columns = append(columns, fitWidth("", columnWidth))
+			continue
+		}
+
+		columns = append(columns, f.styleValue(key, v, fitWidth(f.displayValue(key, v), columnWidth)))
+	}
+
+	body := f.formatBody(entry)
+
+	// Chosen fields are already shown in their columns
+	if len(f.opts.ShowFields) > 0 {
+		body.fields = nil
+	}
+
+	prefix := strings.Join(columns, " ") + " "
+	indent := visibleWidth(prefix)
+	rest := f.joinBody(body)
+
+	width := 0
+	if f.opts.Width > 0 {
+		width = max(f.opts.Width-indent, minMessageWidth)
+	}
+
+	var lines []string
+
+	if f.opts.Wrap {
+		for i, line := range reflow(rest, width) {
+			if i == 0 {
+				lines = append(lines, prefix+line)
+			} else {
+				lines = append(lines, strings.Repeat(" ", indent)+line)
+			}
+		}
+	} else {
+		rest = strings.ReplaceAll(strings.ReplaceAll(rest, "\r\n", " "), "\n", " ")
+		if width > 0 {
+			rest = truncateWidth(rest, width)
+		}
+
+		lines = append(lines, prefix+rest)
+	}
+
+	return strings.Join(append(lines, body.expanded...), "\n")
+}
+
+// fitWidth pads or truncates plain text to exactly width columns
+func fitWidth(text string, width int) string {
+	return padWidth(truncateWidth(text, width), width)
+}
+
+// padWidth pads text with spaces to at least width columns, ignoring any
+// color codes in it
+func padWidth(text string, width int) string {
+	if pad := width - visibleWidth(text); pad > 0 {
+		return text + strings.Repeat(" ", pad)
+	}
+
+	return text
+}
+
+// visibleWidth returns the number of terminal columns text takes up, ignoring
+// any color codes in it
+func visibleWidth(text string) int {
+	width := 0
+
+	for i := 0; i < len(text); {
+		if n := escapeLen(text[i:]); n > 0 {
+			i += n
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		width += runewidth.RuneWidth(r)
+		i += size
+	}
+
+	return width
+}
+
+// truncateWidth shortens text to at most width columns, ending it with an
+// ellipsis when anything was cut. Color codes are kept and reset at the end.
+func truncateWidth(text string, width int) string {
+	if visibleWidth(text) <= width {
+		return text
+	}
+
+	var (
+		b       strings.Builder
+		used    int
+		colored bool
+	)
+
+	limit := width - runewidth.StringWidth(ellipsis)
+
+	for i := 0; i < len(text); {
+		if n := escapeLen(text[i:]); n > 0 {
+			b.WriteString(text[i : i+n])
+			colored = true
+			i += n
+
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		w := runewidth.RuneWidth(r)
+		if used+w > limit {
+			break
+		}
+
+		b.WriteRune(r)
+		used += w
+		i += size
+	}
+
+	if width > 0 {
+		b.WriteString(ellipsis)
+	}
+
+	if colored {
+		b.WriteString(resetSGR)
+	}
+
+	return b.String()
+}
+
+// reflow splits text into lines at its newlines and, when width is above
+// zero, wraps each line at spaces to fit in width columns
+func reflow(text string, width int) []string {
+	var lines []string
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if width > 0 {
+			lines = append(lines, wrapWidth(line, width)...)
+		} else {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// wrapWidth wraps a line of text to width columns, breaking at the last space
+// that fits or, in a word longer than the line, at the width itself. Colors
+// in effect at a break are reset at the end of the line and restored at the
+// start of the next one.
+func wrapWidth(text string, width int) []string {
+	var (
+		lines []string
+		line  []byte
+		used  int
+		// active holds the color codes in effect
+		active string
+		// breakAt is the position in line of the last space, or -1
+		breakAt     = -1
+		breakUsed   int
+		breakActive string
+	)
+
+	endLine := func(text []byte, active string) {
+		if active != "" {
+			text = append(text, resetSGR...)
+		}
+
+		lines = append(lines, string(text))
+	}
+
+	for i := 0; i < len(text); {
+		if n := escapeLen(text[i:]); n > 0 {
+			code := text[i : i+n]
+			if code == resetSGR || code == "\x1b[m" {
+				active = ""
+			} else {
+				active += code
+			}
+
+			line = append(line, code...)
+			i += n
+
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		w := runewidth.RuneWidth(r)
+
+		if used > 0 && used+w > width {
+			switch {
+			case r == ' ':
+				// Break at this space and drop it
+				endLine(line, active)
+				line, used, breakAt = []byte(active), 0, -1
+				i += size
+
+				continue
+			case breakAt >= 0:
+				rest := append([]byte(breakActive), line[breakAt+1:]...)
+				endLine(line[:breakAt], breakActive)
+				line, used, breakAt = rest, used-breakUsed-1, -1
+			default:
+				endLine(line, active)
+				line, used = []byte(active), 0
+			}
+		}
+
+		if r == ' ' {
+			breakAt, breakUsed, breakActive = len(line), used, active
+		}
+
+		line = append(line, text[i:i+size]...)
+		used += w
+		i += size
+	}
+
+	return append(lines, string(line))
+}
+
+// escapeLen returns the length of the ANSI escape sequence at the start of
+// text, or 0 when text does not start with one
+func escapeLen(text string) int {
+	if !strings.HasPrefix(text, "\x1b[") {
+		return 0
+	}
+
+	for i := 2; i < len(text); i++ {
+		switch c := text[i]; {
+		case c >= 0x40 && c <= 0x7e:
+			return i + 1
+		case c < 0x20 || c > 0x3f:
+			return 0
+		}
+	}
+
+	return 0
+}
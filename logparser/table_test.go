@@ -0,0 +1,151 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+const tableInput = `{"level":"warning","ts":1609459200,"msg":"a fairly long message that will not fit","caller":"server/handler.go:42","user":"alice"}`
+
+func TestFormatterTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "unknown width",
+			input:    tableInput,
+			opts:     Options{Output: OutputTable},
+			expected: "2021-01-01 00:00:00 WARN     a fairly long message that will not fit caller=server/handler.go:42 user=alice",
+		},
+		{
+			name:     "truncated to width",
+			input:    tableInput,
+			opts:     Options{Output: OutputTable, Width: 60},
+			expected: "2021-01-01 00:00:00 WARN     a fairly long message that wil…",
+		},
+		{
+			name:     "field columns",
+			input:    tableInput,
+			opts:     Options{Output: OutputTable, ShowFields: []string{"user", "caller", "missing"}, ColumnWidth: 10},
+			expected: "2021-01-01 00:00:00 WARN     alice      server/ha…            a fairly long message that will not fit",
+		},
+		{
+			name:  "wrapped with hanging indent",
+			input: tableInput,
+			opts:  Options{Output: OutputTable, Width: 60, Wrap: true},
+			expected: "2021-01-01 00:00:00 WARN     a fairly long message that will\n" +
+				"                             not fit\n" +
+				"                             caller=server/handler.go:42\n" +
+				"                             user=alice",
+		},
+		{
+			name:     "missing time and long level",
+			input:    `{"level":"verbose-debugging","msg":"line one\nline two"}`,
+			opts:     Options{Output: OutputTable},
+			expected: "                    VERBOSE… line one line two",
+		},
+		{
+			name:  "multi-line message wrapped",
+			input: `{"level":"info","msg":"line one\nline two"}`,
+			opts:  Options{Output: OutputTable, Wrap: true},
+			expected: "                    INFO     line one\n" +
+				"                             line two",
+		},
+		{
+			name:  "expanded fields below the row",
+			input: `{"level":"error","msg":"failed","stack":"first\nsecond"}`,
+			opts:  Options{Output: OutputTable, Width: 40, Expand: true},
+			expected: "                    ERROR    failed\n" +
+				"  stack:\n" +
+				"    first\n" +
+				"    second",
+		},
+	}
+
+	// Epoch times are shown in the local time zone
+	oldLocal := time.Local
+	time.Local = time.UTC
+
+	defer func() { time.Local = oldLocal }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewFormatter(tt.opts).Format(tt.input)
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Format() =\n%q\nwant\n%q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"too long", 5, "too …"},
+		{"日本語テキスト", 7, "日本語…"},
+		{"\x1b[31mred text\x1b[0m", 4, "\x1b[31mred…\x1b[0m"},
+		{"anything", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := truncateWidth(tt.text, tt.width); got != tt.expected {
+				t.Errorf("truncateWidth(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWrapWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		width    int
+		expected []string
+	}{
+		{"fits", "one two", 10, []string{"one two"}},
+		{"breaks at spaces", "one two three four", 9, []string{"one two", "three", "four"}},
+		{"breaks long words", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"drops space at break", "abcd efgh", 4, []string{"abcd", "efgh"}},
+		{
+			name:     "carries colors across breaks",
+			text:     "plain \x1b[31mred words\x1b[0m end",
+			width:    9,
+			expected: []string{"plain \x1b[31mred\x1b[0m", "\x1b[31mwords\x1b[0m end"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapWidth(tt.text, tt.width)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("wrapWidth(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.expected)
+			}
+
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("wrapWidth(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.expected)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	if got := visibleWidth("\x1b[1;31mab\x1b[0m日"); got != 4 {
+		t.Errorf("visibleWidth() = %d, want 4", got)
+	}
+}
@@ -0,0 +1,393 @@
+package logparser
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// DefaultThemeName is the theme used when none is chosen
+const DefaultThemeName = "dark"
+
+// ThemeRoles lists the parts of the output a theme styles
+var ThemeRoles = []string{
+	"time", "message", "key", "value", "highlight",
+	"level_trace", "level_debug", "level_info", "level_notice", "level_warn",
+	"level_error", "level_critical", "level_fatal", "level_panic", "level_unknown",
+	"json_key", "json_string", "json_number", "json_literal",
+	"frame_header", "frame_own", "frame_stdlib",
+}
+
+// builtinThemes are the style specs of the built-in themes, by role
+var builtinThemes = map[string]map[string]string{
+	"dark": {
+		"time": "cyan", "message": "white", "key": "magenta", "value": "yellow", "highlight": "bold black on yellow",
+		"level_trace": "magenta", "level_debug": "blue", "level_info": "green", "level_notice": "cyan",
+		"level_warn": "yellow", "level_error": "red", "level_critical": "bold red",
+		"level_fatal": "bold hi-white on red", "level_panic": "bold hi-white on red", "level_unknown": "white",
+		"json_key": "magenta", "json_string": "yellow", "json_number": "cyan", "json_literal": "blue",
+		"frame_header": "red", "frame_own": "bold hi-white", "frame_stdlib": "faint",
+	},
+	"light": {
+		"time": "blue", "message": "", "key": "magenta", "value": "130", "highlight": "black on 229",
+		"level_trace": "magenta", "level_debug": "blue", "level_info": "28", "level_notice": "30",
+		"level_warn": "166", "level_error": "red", "level_critical": "bold red",
+		"level_fatal": "bold white on red", "level_panic": "bold white on red", "level_unknown": "",
+		"json_key": "magenta", "json_string": "130", "json_number": "blue", "json_literal": "30",
+		"frame_header": "red", "frame_own": "bold black", "frame_stdlib": "faint",
+	},
+	"solarized": {
+		"time": "#2aa198", "message": "#839496", "key": "#6c71c4", "value": "#b58900", "highlight": "#002b36 on #b58900",
+		"level_trace": "#6c71c4", "level_debug": "#268bd2", "level_info": "#859900", "level_notice": "#2aa198",
+		"level_warn": "#cb4b16", "level_error": "#dc322f", "level_critical": "bold #dc322f",
+		"level_fatal": "bold #fdf6e3 on #dc322f", "level_panic": "bold #fdf6e3 on #dc322f", "level_unknown": "#839496",
+		"json_key": "#6c71c4", "json_string": "#b58900", "json_number": "#2aa198", "json_literal": "#268bd2",
+		"frame_header": "#dc322f", "frame_own": "bold #93a1a1", "frame_stdlib": "#586e75",
+	},
+	"high-contrast": {
+		"time": "hi-cyan", "message": "hi-white", "key": "bold hi-magenta", "value": "hi-yellow", "highlight": "bold black on hi-yellow",
+		"level_trace": "hi-magenta", "level_debug": "hi-blue", "level_info": "bold hi-green", "level_notice": "bold hi-cyan",
+		"level_warn": "bold black on hi-yellow", "level_error": "bold hi-white on red", "level_critical": "bold underline hi-white on red",
+		"level_fatal": "bold underline hi-white on red", "level_panic": "bold underline hi-white on red", "level_unknown": "hi-white",
+		"json_key": "bold hi-magenta", "json_string": "hi-yellow", "json_number": "hi-cyan", "json_literal": "hi-blue",
+		"frame_header": "bold hi-red", "frame_own": "bold underline hi-white", "frame_stdlib": "white",
+	},
+}
+
+// builtinPalettes are the styles of the built-in themes used to tell values
+// apart by their hash, chosen to be readable on the theme's background
+var builtinPalettes = map[string]string{
+	"dark":          "39, 41, 45, 75, 78, 111, 114, 141, 147, 170, 173, 178, 180, 204, 207, 214",
+	"light":         "18, 22, 24, 25, 28, 30, 54, 55, 88, 90, 94, 124, 127, 130, 166, 202",
+	"solarized":     "#b58900, #cb4b16, #dc322f, #d33682, #6c71c4, #268bd2, #2aa198, #859900",
+	"high-contrast": "hi-red, hi-green, hi-yellow, hi-blue, hi-magenta, hi-cyan, bold hi-red, bold hi-green, bold hi-yellow, bold hi-blue, bold hi-magenta, bold hi-cyan",
+}
+
+// defaultTheme is used by formatters created without a theme
+var defaultTheme = mustTheme(DefaultThemeName)
+
+// plainTheme leaves every role unstyled, for output without color
+var plainTheme = &Theme{}
+
+// Theme holds the style of each part of the output
+type Theme struct {
+	styles map[string]Style
+	// palette holds the styles that values are hashed to, see
+	// Options.HashFields
+	palette []Style
+}
+
+// ThemeNames returns the names of the built-in themes in sorted order
+func ThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// BuiltinTheme returns one of the built-in themes
+func BuiltinTheme(name string) (*Theme, error) {
+	specs, ok := builtinThemes[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q (expected one of %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	theme, err := newTheme(nil, specs)
+	if err != nil {
+		return nil, err
+	}
+
+	theme.palette, err = parseStyles(builtinPalettes[strings.ToLower(name)])
+	if err != nil {
+		return nil, fmt.Errorf("theme palette: %w", err)
+	}
+
+	return theme, nil
+}
+
+// ParseTheme builds a theme from style specs keyed by role. The "base" key
+// names the built-in theme providing the roles that are not given, by
+// default DefaultThemeName, and the "palette" key replaces the styles values
+// are hashed to with a comma-separated list.
+func ParseTheme(specs map[string]string) (*Theme, error) {
+	baseName := specs["base"]
+	if baseName == "" {
+		baseName = DefaultThemeName
+	}
+
+	base, err := BuiltinTheme(baseName)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTheme(base, specs)
+}
+
+// newTheme copies base, when given, and applies specs on top of it
+func newTheme(base *Theme, specs map[string]string) (*Theme, error) {
+	theme := &Theme{styles: make(map[string]Style, len(ThemeRoles))}
+
+	if base != nil {
+		for role, style := range base.styles {
+			theme.styles[role] = style
+		}
+
+		theme.palette = base.palette
+	}
+
+	for role, spec := range specs {
+		switch role {
+		case "base":
+			continue
+		case "palette":
+			palette, err := parseStyles(spec)
+			if err != nil {
+				return nil, fmt.Errorf("theme palette: %w", err)
+			}
+
+			theme.palette = palette
+
+			continue
+		}
+
+		if !isThemeRole(role) {
+			return nil, fmt.Errorf("unknown theme role %q", role)
+		}
+
+		style, err := ParseStyle(spec)
+		if err != nil {
+			return nil, fmt.Errorf("theme role %s: %w", role, err)
+		}
+
+		theme.styles[role] = style
+	}
+
+	return theme, nil
+}
+
+// mustTheme returns a built-in theme, panicking if its specs are invalid
+func mustTheme(name string) *Theme {
+	theme, err := BuiltinTheme(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return theme
+}
+
+// isThemeRole reports whether role is one of ThemeRoles
+func isThemeRole(role string) bool {
+	for _, known := range ThemeRoles {
+		if role == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sprint styles text in the style of a role
+func (t *Theme) sprint(role, text string) string {
+	return t.styles[role].Sprint(text)
+}
+
+// hashStyle returns the palette style for a value, the same for every
+// occurrence of the value
+func (t *Theme) hashStyle(value string) Style {
+	if len(t.palette) == 0 {
+		return Style{}
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(value))
+
+	return t.palette[hash.Sum32()%uint32(len(t.palette))]
+}
+
+// PaletteStyle returns the style at index i of the palette, wrapping around,
+// for telling a fixed set of things apart such as the files of a merged
+// stream. A nil theme is the default theme.
+func (t *Theme) PaletteStyle(i int) Style {
+	if t == nil {
+		t = defaultTheme
+	}
+
+	if len(t.palette) == 0 {
+		return Style{}
+	}
+
+	return t.palette[i%len(t.palette)]
+}
+
+// formatLevel returns a level in upper case, styled by its severity
+func (t *Theme) formatLevel(level string) string {
+	level = strings.ToUpper(level)
+
+	if logLevel, ok := lookupLogLevel(level); ok {
+		return t.sprint("level_"+strings.ToLower(logLevel.String()), level)
+	}
+
+	return t.sprint("level_unknown", level)
+}
+
+// Style is a combination of colors and text attributes. The zero Style leaves
+// text unchanged.
+type Style struct {
+	// sequence holds the SGR parameters of the style, such as "1;31"
+	sequence string
+}
+
+// ParseStyle parses a style spec: space-separated attributes (bold, faint or
+// dim, italic, underline, blink, reverse, strikethrough) and colors, where a
+// color after "on" is the background. Colors are names such as red or
+// hi-red, 256-color palette numbers from 0 to 255, or #rgb and #rrggbb hex
+// truecolor. An empty spec, or "none", is plain text.
+func ParseStyle(spec string) (Style, error) {
+	var (
+		params     []string
+		background bool
+	)
+
+	add := func(value ...color.Attribute) {
+		for _, v := range value {
+			params = append(params, strconv.Itoa(int(v)))
+		}
+	}
+
+	for _, token := range strings.Fields(strings.ToLower(spec)) {
+		if attribute, ok := styleAttributes[token]; ok {
+			add(attribute)
+			continue
+		}
+
+		switch token {
+		case "none", "default":
+			continue
+		case "on":
+			background = true
+			continue
+		}
+
+		value, err := parseColor(token, background)
+		if err != nil {
+			return Style{}, err
+		}
+
+		add(value...)
+
+		background = false
+	}
+
+	if background {
+		return Style{}, fmt.Errorf("invalid style %q: missing background color after \"on\"", spec)
+	}
+
+	return Style{sequence: strings.Join(params, ";")}, nil
+}
+
+// Sprint styles text
+func (s Style) Sprint(text string) string {
+	if s.sequence == "" {
+		return text
+	}
+
+	return "\x1b[" + s.sequence + "m" + text + resetSGR
+}
+
+// styleAttributes are the text attributes of a style spec
+var styleAttributes = map[string]color.Attribute{
+	"bold":          color.Bold,
+	"faint":         color.Faint,
+	"dim":           color.Faint,
+	"italic":        color.Italic,
+	"underline":     color.Underline,
+	"blink":         color.BlinkSlow,
+	"reverse":       color.ReverseVideo,
+	"strikethrough": color.CrossedOut,
+}
+
+// colorNames are the foreground colors of the basic 16-color palette
+var colorNames = map[string]color.Attribute{
+	"black":      color.FgBlack,
+	"red":        color.FgRed,
+	"green":      color.FgGreen,
+	"yellow":     color.FgYellow,
+	"blue":       color.FgBlue,
+	"magenta":    color.FgMagenta,
+	"cyan":       color.FgCyan,
+	"white":      color.FgWhite,
+	"gray":       color.FgHiBlack,
+	"grey":       color.FgHiBlack,
+	"hi-black":   color.FgHiBlack,
+	"hi-red":     color.FgHiRed,
+	"hi-green":   color.FgHiGreen,
+	"hi-yellow":  color.FgHiYellow,
+	"hi-blue":    color.FgHiBlue,
+	"hi-magenta": color.FgHiMagenta,
+	"hi-cyan":    color.FgHiCyan,
+	"hi-white":   color.FgHiWhite,
+}
+
+// backgroundOffset turns a foreground color attribute into a background one
+const backgroundOffset = color.BgBlack - color.FgBlack
+
+// parseColor converts a color token to SGR attributes
+func parseColor(token string, background bool) ([]color.Attribute, error) {
+	if attribute, ok := colorNames[strings.Replace(token, "bright-", "hi-", 1)]; ok {
+		if background {
+			attribute += backgroundOffset
+		}
+
+		return []color.Attribute{attribute}, nil
+	}
+
+	if strings.HasPrefix(token, "#") {
+		r, g, b, err := parseHexColor(token)
+		if err != nil {
+			return nil, err
+		}
+
+		if background {
+			return []color.Attribute{48, 2, color.Attribute(r), color.Attribute(g), color.Attribute(b)}, nil
+		}
+
+		return []color.Attribute{38, 2, color.Attribute(r), color.Attribute(g), color.Attribute(b)}, nil
+	}
+
+	if n, err := strconv.Atoi(token); err == nil {
+		if n < 0 || n > 255 {
+			return nil, fmt.Errorf("invalid color %q: palette colors are 0 to 255", token)
+		}
+
+		if background {
+			return []color.Attribute{48, 5, color.Attribute(n)}, nil
+		}
+
+		return []color.Attribute{38, 5, color.Attribute(n)}, nil
+	}
+
+	return nil, fmt.Errorf("unknown color or attribute %q", token)
+}
+
+// parseHexColor parses a #rgb or #rrggbb color
+func parseHexColor(token string) (r, g, b int, err error) {
+	hex := strings.TrimPrefix(token, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	value, parseErr := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || parseErr != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hex color %q (expected #rgb or #rrggbb)", token)
+	}
+
+	return int(value >> 16), int(value >> 8 & 0xff), int(value & 0xff), nil
+}
@@ -0,0 +1,170 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStyle(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{"", "x"},
+		{"none", "x"},
+		{"red", "\x1b[31mx\x1b[0m"},
+		{"Bold Red", "\x1b[1;31mx\x1b[0m"},
+		{"hi-white on red", "\x1b[97;41mx\x1b[0m"},
+		{"bright-blue", "\x1b[94mx\x1b[0m"},
+		{"italic underline 208", "\x1b[3;4;38;5;208mx\x1b[0m"},
+		{"#ff8800 on 236", "\x1b[38;2;255;136;0;48;5;236mx\x1b[0m"},
+		{"#0f8", "\x1b[38;2;0;255;136mx\x1b[0m"},
+		{"faint", "\x1b[2mx\x1b[0m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			style, err := ParseStyle(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseStyle(%q) error: %v", tt.spec, err)
+			}
+
+			if got := style.Sprint("x"); got != tt.expected {
+				t.Errorf("ParseStyle(%q).Sprint() = %q, want %q", tt.spec, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseStyleErrors(t *testing.T) {
+	for _, spec := range []string{"purple", "256", "-1", "#12345", "#ggg", "red on"} {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := ParseStyle(spec); err == nil {
+				t.Errorf("ParseStyle(%q) expected an error", spec)
+			}
+		})
+	}
+}
+
+func TestBuiltinThemes(t *testing.T) {
+	for _, name := range ThemeNames() {
+		t.Run(name, func(t *testing.T) {
+			if _, err := BuiltinTheme(name); err != nil {
+				t.Fatalf("BuiltinTheme(%q) error: %v", name, err)
+			}
+
+			for _, role := range ThemeRoles {
+				if _, ok := builtinThemes[name][role]; !ok {
+					t.Errorf("theme %s does not define %s", name, role)
+				}
+			}
+
+			if theme, _ := BuiltinTheme(name); len(theme.palette) < 8 {
+				t.Errorf("theme %s has a palette of %d styles, want at least 8", name, len(theme.palette))
+			}
+		})
+	}
+
+	if _, err := BuiltinTheme("neon"); err == nil || !strings.Contains(err.Error(), "dark, high-contrast, light, solarized") {
+		t.Errorf("BuiltinTheme(neon) error = %v, want the list of themes", err)
+	}
+}
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme(map[string]string{"base": "light", "time": "#123456"})
+	if err != nil {
+		t.Fatalf("ParseTheme() error: %v", err)
+	}
+
+	if got := theme.sprint("time", "t"); got != "\x1b[38;2;18;52;86mt\x1b[0m" {
+		t.Errorf("time style = %q", got)
+	}
+
+	// Roles that are not given come from the base theme
+	if got := theme.sprint("message", "m"); got != "m" {
+		t.Errorf("message style = %q, want the light theme's plain text", got)
+	}
+
+	// The palette comes from the base theme unless replaced
+	if len(theme.palette) != len(mustTheme("light").palette) {
+		t.Errorf("palette has %d styles, want the light theme's", len(theme.palette))
+	}
+
+	theme, err = ParseTheme(map[string]string{"palette": "red, bold #00ff00"})
+	if err != nil {
+		t.Fatalf("ParseTheme() error: %v", err)
+	}
+
+	if len(theme.palette) != 2 || theme.palette[1].sequence != "1;38;2;0;255;0" {
+		t.Errorf("palette = %+v, want red and bold #00ff00", theme.palette)
+	}
+
+	for _, specs := range []map[string]string{
+		{"palette": "red, purple"},
+		{"base": "neon"},
+		{"tiem": "red"},
+		{"time": "purple"},
+	} {
+		if _, err := ParseTheme(specs); err == nil {
+			t.Errorf("ParseTheme(%v) expected an error", specs)
+		}
+	}
+}
+
+func TestFormatterUsesTheme(t *testing.T) {
+	theme, err := ParseTheme(map[string]string{"key": "underline", "value": "italic", "level_error": "reverse"})
+	if err != nil {
+		t.Fatalf("ParseTheme() error: %v", err)
+	}
+
+	result, err := NewFormatter(Options{Theme: theme, Color: ColorAlways}).Format(`{"level":"error","msg":"failed","user":"alice"}`)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+
+	for _, want := range []string{"\x1b[7mERROR\x1b[0m", "\x1b[4muser\x1b[0m=", "\x1b[3malice\x1b[0m"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Format() missing %q\nGot: %q", want, result)
+		}
+	}
+}
+
+func TestHashFields(t *testing.T) {
+	formatter := NewFormatter(Options{Color: ColorAlways, HashFields: []string{"request_id", "http.pod"}})
+
+	styleOf := func(line, value string) string {
+		t.Helper()
+
+		result, err := formatter.Format(line)
+		if err != nil {
+			t.Fatalf("Format() error: %v", err)
+		}
+
+		for _, style := range defaultTheme.palette {
+			if strings.Contains(result, "="+style.Sprint(value)) {
+				return style.sequence
+			}
+		}
+
+		t.Fatalf("Format() = %q, want %s in a palette color", result, value)
+
+		return ""
+	}
+
+	first := styleOf(`{"msg":"start","request_id":"abc123"}`, "abc123")
+	if again := styleOf(`{"msg":"done","user":"bob","request_id":"abc123"}`, "abc123"); again != first {
+		t.Errorf("request_id abc123 styled %q then %q, want the same color", first, again)
+	}
+
+	styleOf(`{"msg":"nested","http":{"pod":"web-1"}}`, "web-1")
+
+	// Other fields keep the theme's value style
+	result, err := formatter.Format(`{"msg":"x","user":"abc123"}`)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+
+	if !strings.Contains(result, "="+defaultTheme.sprint("value", "abc123")) {
+		t.Errorf("Format() = %q, want user in the value style", result)
+	}
+}
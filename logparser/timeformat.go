@@ -0,0 +1,327 @@
+package logparser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// DefaultTimeLayout is the layout times are shown in when no format is chosen
+const DefaultTimeLayout = "2006-01-02 15:04:05"
+
+// relativeTimeWidth is the width of the time column in the table format for
+// the relative and delta time formats
+const relativeTimeWidth = 12
+
+// timeMode selects how a TimeFormat shows a time
+type timeMode int
+
+const (
+	// timeAbsolute formats the time with a layout
+	timeAbsolute timeMode = iota
+	// timeRelative shows how long ago the time was
+	timeRelative
+	// timeDelta shows the time since the previous entry
+	timeDelta
+)
+
+// timeFormatPresets are the named time formats, by name
+var timeFormatPresets = map[string]string{
+	"default":     DefaultTimeLayout,
+	"iso":         "2006-01-02T15:04:05Z07:00",
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"kitchen":     time.Kitchen,
+	"stamp":       time.Stamp,
+	"time":        "15:04:05",
+}
+
+// timePrecisions are the precisions times can be shown with, by name
+var timePrecisions = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// strftimeDirectives are the Go layout elements of the supported strftime
+// directives
+var strftimeDirectives = map[byte]string{
+	'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'e': "_2", 'j': "002",
+	'H': "15", 'I': "03", 'M': "04", 'S': "05", 'p': "PM",
+	'L': "000", 'f': "000000",
+	'b': "Jan", 'h': "Jan", 'B': "January", 'a': "Mon", 'A': "Monday",
+	'Z': "MST", 'z': "-0700",
+	'F': "2006-01-02", 'T': "15:04:05", 'D': "01/02/06", 'R': "15:04",
+	'%': "%",
+}
+
+// fractionPattern matches the fractional seconds of a layout
+var fractionPattern = regexp.MustCompile(`05[.,](0+|9+)`)
+
+// TimeFormat controls how the times of entries and converted timestamp
+// fields are shown. The zero TimeFormat uses DefaultTimeLayout.
+type TimeFormat struct {
+	layout string
+	mode   timeMode
+	// precision rounds relative times; zero means whole seconds
+	precision time.Duration
+}
+
+// TimeFormatNames returns the names of the preset time formats in sorted
+// order, including relative and delta
+func TimeFormatNames() []string {
+	names := []string{"relative", "delta"}
+	for name := range timeFormatPresets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ParseTimeFormat parses a time format and precision. The format is a preset
+// name (see TimeFormatNames), a strftime format such as "%H:%M:%S", or a Go
+// layout such as "Jan _2 15:04:05". relative shows how long ago each time was
+// ("3m12s ago") and delta the time since the previous entry ("+1.5s"). The
+// precision is s, ms, us or ns: fractional seconds are added to or removed
+// from the layout, and relative times are rounded to it. An empty precision
+// leaves the layout as it is.
+func ParseTimeFormat(format, precision string) (TimeFormat, error) {
+	var tf TimeFormat
+
+	switch name := strings.ToLower(strings.TrimSpace(format)); {
+	case name == "":
+		tf.layout = DefaultTimeLayout
+	case name == "relative":
+		tf.mode = timeRelative
+	case name == "delta":
+		tf.mode = timeDelta
+	case timeFormatPresets[name] != "":
+		tf.layout = timeFormatPresets[name]
+	case strings.Contains(format, "%"):
+		layout, err := strftimeLayout(format)
+		if err != nil {
+			return TimeFormat{}, err
+		}
+
+		tf.layout = layout
+	default:
+		tf.layout = format
+	}
+
+	if precision == "" {
+		return tf, nil
+	}
+
+	unit, ok := timePrecisions[strings.ToLower(strings.TrimSpace(precision))]
+	if !ok {
+		return TimeFormat{}, fmt.Errorf("unknown time precision %q (expected s, ms, us or ns)", precision)
+	}
+
+	tf.precision = unit
+
+	if tf.mode == timeAbsolute {
+		tf.layout = withPrecision(tf.layout, unit)
+	}
+
+	return tf, nil
+}
+
+// strftimeLayout converts a strftime format to a Go layout
+func strftimeLayout(format string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+
+		if i+1 == len(format) {
+			return "", fmt.Errorf("invalid time format %q: trailing %%", format)
+		}
+
+		i++
+
+		element, ok := strftimeDirectives[format[i]]
+		if !ok {
+			return "", fmt.Errorf("invalid time format %q: unsupported directive %%%c", format, format[i])
+		}
+
+		b.WriteString(element)
+	}
+
+	return b.String(), nil
+}
+
+// withPrecision replaces the fractional seconds of a layout with the digits
+// of a precision. Layouts without seconds are left unchanged.
+func withPrecision(layout string, unit time.Duration) string {
+	layout = fractionPattern.ReplaceAllString(layout, "05")
+
+	digits := 0
+	for d := unit; d < time.Second; d *= 10 {
+		digits++
+	}
+
+	if digits == 0 {
+		return layout
+	}
+
+	return strings.Replace(layout, "05", "05."+strings.Repeat("0", digits), 1)
+}
+
+// format shows a time, with now as the reference of relative times
+func (tf TimeFormat) format(t, now time.Time) string {
+	switch tf.mode {
+	case timeRelative, timeDelta:
+		d := now.Sub(t)
+		if d < 0 {
+			return "in " + tf.duration(-d)
+		}
+
+		return tf.duration(d) + " ago"
+	default:
+		layout := tf.layout
+		if layout == "" {
+			layout = DefaultTimeLayout
+		}
+
+		return t.Format(layout)
+	}
+}
+
+// delta shows the time between an entry and the previous one
+func (tf TimeFormat) delta(t, previous time.Time) string {
+	d := t.Sub(previous)
+	if d < 0 {
+		return "-" + tf.duration(-d)
+	}
+
+	return "+" + tf.duration(d)
+}
+
+// duration rounds a duration to the precision, counts whole days and drops
+// trailing zero units, so that 49h0m0s is shown as 2d1h
+func (tf TimeFormat) duration(d time.Duration) string {
+	unit := tf.precision
+	if unit == 0 {
+		unit = time.Second
+	}
+
+	d = d.Round(unit)
+
+	var days string
+	if d >= 24*time.Hour {
+		days = fmt.Sprintf("%dd", d/(24*time.Hour))
+
+		d %= 24 * time.Hour
+		if d == 0 {
+			return days
+		}
+	}
+
+	text := days + d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+
+	return text
+}
+
+// width returns the most columns a time takes up in this format, for the
+// time column of the table format
+func (tf TimeFormat) width(location *time.Location) int {
+	if tf.mode != timeAbsolute {
+		return relativeTimeWidth
+	}
+
+	if location == nil {
+		location = time.Local
+	}
+
+	// A Wednesday in September evening has the longest names and numbers
+	sample := time.Date(2023, time.September, 27, 22, 44, 44, 444444444, location)
+
+	return runewidth.StringWidth(tf.format(sample, sample))
+}
+
+// PreviousTime remembers the time of the last entry shown, for the delta time
+// format. Formatters sharing one measure the delta across all their entries.
+type PreviousTime struct {
+	time time.Time
+	set  bool
+}
+
+// formatEntryTime formats the time of an entry. In the delta format it is
+// shown as the time since the previous entry, starting from +0s.
+func (f *Formatter) formatEntryTime(value interface{}) string {
+	if f.opts.TimeFormat.mode != timeDelta {
+		return f.formatTimeValue(value)
+	}
+
+	parsed, ok := f.parseTime(value)
+	if !ok {
+		return formatTime(value)
+	}
+
+	previous := f.previous
+	if !previous.set {
+		previous.time, previous.set = parsed, true
+	}
+
+	text := f.opts.TimeFormat.delta(parsed, previous.time)
+	previous.time = parsed
+
+	return text
+}
+
+// formatTimeValue formats a time in the TimeFormat and Location, leaving
+// values that are not times as they are
+func (f *Formatter) formatTimeValue(value interface{}) string {
+	parsed, ok := f.parseTime(value)
+	if !ok {
+		return formatTime(value)
+	}
+
+	return f.opts.TimeFormat.format(f.inLocation(parsed), f.now())
+}
+
+// inLocation converts a time to the Location, when one is set
+func (f *Formatter) inLocation(t time.Time) time.Time {
+	if f.opts.Location == nil {
+		return t
+	}
+
+	return t.In(f.opts.Location)
+}
+
+// ParseLocation converts a time zone name to a location: UTC, Local, or an
+// IANA name such as Europe/London
+func ParseLocation(name string) (*time.Location, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "utc", "z":
+		return time.UTC, nil
+	case "local":
+		return time.Local, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+
+	return location, nil
+}
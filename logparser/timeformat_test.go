@@ -0,0 +1,156 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeFormat(t *testing.T) {
+	at := time.Date(2024, time.March, 1, 14, 5, 9, 123456789, time.UTC)
+
+	tests := []struct {
+		format    string
+		precision string
+		expected  string
+	}{
+		{"", "", "2024-03-01 14:05:09"},
+		{"", "ms", "2024-03-01 14:05:09.123"},
+		{"iso", "", "2024-03-01T14:05:09Z"},
+		{"iso", "us", "2024-03-01T14:05:09.123456Z"},
+		{"RFC3339Nano", "", "2024-03-01T14:05:09.123456789Z"},
+		{"rfc3339nano", "s", "2024-03-01T14:05:09Z"},
+		{"rfc3339nano", "ms", "2024-03-01T14:05:09.123Z"},
+		{"kitchen", "ms", "2:05PM"},
+		{"time", "ns", "14:05:09.123456789"},
+		{"%Y/%m/%d %H:%M:%S.%L %Z", "", "2024/03/01 14:05:09.123 UTC"},
+		{"%a %e %b %I:%M %p %%", "", "Fri  1 Mar 02:05 PM %"},
+		{"%F %T", "ms", "2024-03-01 14:05:09.123"},
+		{"Jan _2 15:04", "", "Mar  1 14:05"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.precision, func(t *testing.T) {
+			tf, err := ParseTimeFormat(tt.format, tt.precision)
+			if err != nil {
+				t.Fatalf("ParseTimeFormat(%q, %q) error: %v", tt.format, tt.precision, err)
+			}
+
+			if got := tf.format(at, at); got != tt.expected {
+				t.Errorf("format() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTimeFormatErrors(t *testing.T) {
+	tests := []struct {
+		format    string
+		precision string
+	}{
+		{"%Q", ""},
+		{"%H:%", ""},
+		{"iso", "minutes"},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseTimeFormat(tt.format, tt.precision); err == nil {
+			t.Errorf("ParseTimeFormat(%q, %q) expected an error", tt.format, tt.precision)
+		}
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		precision string
+		ago       time.Duration
+		expected  string
+	}{
+		{"", 3*time.Minute + 12*time.Second + 400*time.Millisecond, "3m12s ago"},
+		{"ms", 3*time.Minute + 12*time.Second + 400*time.Millisecond, "3m12.4s ago"},
+		{"", 2 * time.Hour, "2h ago"},
+		{"", 49*time.Hour + 30*time.Second, "2d1h0m30s ago"},
+		{"", 48 * time.Hour, "2d ago"},
+		{"", -90 * time.Second, "in 1m30s"},
+		{"", 0, "0s ago"},
+	}
+
+	for _, tt := range tests {
+		tf, err := ParseTimeFormat("relative", tt.precision)
+		if err != nil {
+			t.Fatalf("ParseTimeFormat() error: %v", err)
+		}
+
+		if got := tf.format(now.Add(-tt.ago), now); got != tt.expected {
+			t.Errorf("format(%v ago) = %q, want %q", tt.ago, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatterTimeFormat(t *testing.T) {
+	london, err := ParseLocation("Europe/London")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+
+	iso, _ := ParseTimeFormat("iso", "")
+
+	formatter := NewFormatter(Options{TimeFormat: iso, Location: london, ConvertTimestamps: true, TimestampFields: []string{"expires"}})
+
+	result, err := formatter.Format(`{"time":"2024-07-01T12:00:00Z","msg":"summer","expires":"2024-06-01T00:00:00Z"}`)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+
+	if expected := "2024-07-01T13:00:00+01:00 summer expires=2024-06-01T01:00:00+01:00 (2024-06-01T00:00:00Z)"; result != expected {
+		t.Errorf("Format() = %q, want %q", result, expected)
+	}
+
+	delta, _ := ParseTimeFormat("delta", "ms")
+	formatter = NewFormatter(Options{TimeFormat: delta})
+
+	for i, tt := range []struct {
+		line     string
+		expected string
+	}{
+		{`{"time":"2024-03-01T12:00:00Z","msg":"a"}`, "+0s a"},
+		{`{"time":"2024-03-01T12:00:01.25Z","msg":"b"}`, "+1.25s b"},
+		{`{"msg":"no time"}`, "no time"},
+		{`{"time":"2024-03-01T12:00:01Z","msg":"c"}`, "-250ms c"},
+	} {
+		result, err := formatter.Format(tt.line)
+		if err != nil {
+			t.Fatalf("Format() error: %v", err)
+		}
+
+		if result != tt.expected {
+			t.Errorf("line %d: Format() = %q, want %q", i, result, tt.expected)
+		}
+	}
+
+	relative, _ := ParseTimeFormat("relative", "")
+	formatter = NewFormatter(Options{TimeFormat: relative})
+	formatter.now = func() time.Time { return time.Date(2024, time.March, 1, 12, 3, 12, 0, time.UTC) }
+
+	result, err = formatter.Format(`{"time":"2024-03-01T12:00:00Z","msg":"a"}`)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+
+	if result != "3m12s ago a" {
+		t.Errorf("Format() = %q, want %q", result, "3m12s ago a")
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	for _, name := range []string{"UTC", "utc", "Local"} {
+		if _, err := ParseLocation(name); err != nil {
+			t.Errorf("ParseLocation(%q) error: %v", name, err)
+		}
+	}
+
+	if _, err := ParseLocation("Mars/Olympus_Mons"); err == nil {
+		t.Error("ParseLocation(Mars/Olympus_Mons) expected an error")
+	}
+}
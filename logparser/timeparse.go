@@ -0,0 +1,227 @@
+package logparser
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// epochLimit is the largest Unix timestamp read as seconds, in the year 2286.
+// Larger values are read as milliseconds, then microseconds, then
+// nanoseconds, each up to the same date.
+const epochLimit = 1e10
+
+// timeLayouts are the layouts tried in turn when parsing time strings.
+// Fractional seconds, with a dot or a comma, are accepted after the seconds
+// of any of them.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -0700 MST",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	// Apache and nginx access logs
+	"02/Jan/2006:15:04:05 -0700",
+	"02/Jan/2006:15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.RFC822Z,
+	time.RFC822,
+	time.RubyDate,
+	time.UnixDate,
+	time.ANSIC,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Jan _2 2006 15:04:05",
+	"2006-01-02",
+}
+
+// yearlessLayouts are syslog layouts without a year, which is taken to be
+// the most recent one that does not put the time in the future
+var yearlessLayouts = []string{
+	time.Stamp,
+	"Jan _2 15:04:05 -0700",
+	"Mon Jan _2 15:04:05",
+}
+
+// ParseTime converts a Unix timestamp or a time string to a time.Time.
+// Timestamps may be numbers or numeric strings in seconds, milliseconds,
+// microseconds or nanoseconds, told apart by their size; strings may be in
+// RFC 3339 or one of many common layouts, such as those of Apache, nginx and
+// syslog.
+func ParseTime(timeVal interface{}) (time.Time, bool) {
+	switch t := timeVal.(type) {
+	case float64:
+		return parseEpoch(t)
+	case int:
+		return parseEpoch(float64(t))
+	case int64:
+		return parseEpochInt(t)
+	case json.Number:
+		return parseTimeString(t.String())
+	case string:
+		return parseTimeString(t)
+	default:
+		return time.Time{}, false
+	}
+}
+
+// parseEpoch converts a Unix timestamp, keeping any fraction of its unit
+func parseEpoch(value float64) (time.Time, bool) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return time.Time{}, false
+	}
+
+	if value == math.Trunc(value) && math.Abs(value) < math.MaxInt64 {
+		return parseEpochInt(int64(value))
+	}
+
+	unit := epochUnit(math.Abs(value))
+
+	whole, fraction := math.Modf(value)
+
+	return epochTime(int64(whole), unit).Add(time.Duration(fraction * float64(unit))), true
+}
+
+// parseEpochInt converts a whole Unix timestamp
+func parseEpochInt(value int64) (time.Time, bool) {
+	magnitude := value
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+
+	return epochTime(value, epochUnit(float64(magnitude))), true
+}
+
+// epochTime converts a whole number of units since the Unix epoch without
+// overflowing, as milliseconds and microseconds up to the epoch limit do not
+// fit in int64 nanoseconds
+func epochTime(value int64, unit time.Duration) time.Time {
+	switch unit {
+	case time.Second:
+		return time.Unix(value, 0)
+	case time.Millisecond:
+		return time.UnixMilli(value)
+	case time.Microsecond:
+		return time.UnixMicro(value)
+	default:
+		return time.Unix(0, value)
+	}
+}
+
+// epochUnit returns the unit of a Unix timestamp of the given size
+func epochUnit(magnitude float64) time.Duration {
+	switch {
+	case magnitude <= epochLimit:
+		return time.Second
+	case magnitude <= epochLimit*1e3:
+		return time.Millisecond
+	case magnitude <= epochLimit*1e6:
+		return time.Microsecond
+	default:
+		return time.Nanosecond
+	}
+}
+
+// parseTimeString parses a numeric timestamp or a time in one of the known
+// layouts
+func parseTimeString(text string) (time.Time, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return time.Time{}, false
+	}
+
+	if isEpochString(text) {
+		if whole, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return parseEpochInt(whole)
+		}
+
+		if value, err := strconv.ParseFloat(text, 64); err == nil {
+			return parseEpoch(value)
+		}
+	}
+
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			return parsed, true
+		}
+	}
+
+	for _, layout := range yearlessLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			return withRecentYear(parsed, time.Now()), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// isEpochString reports whether text is a plain decimal number, as opposed
+// to a date such as 2024-03-01
+func isEpochString(text string) bool {
+	text = strings.TrimPrefix(text, "-")
+
+	whole, fraction, _ := strings.Cut(text, ".")
+	if whole == "" {
+		return false
+	}
+
+	for _, part := range []string{whole, fraction} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// withRecentYear sets the year of a time parsed without one to that of now,
+// or the year before when that would be more than a day in the future
+func withRecentYear(parsed, now time.Time) time.Time {
+	year := now.Year()
+
+	dated := parsed.AddDate(year-parsed.Year(), 0, 0)
+	if dated.After(now.Add(24 * time.Hour)) {
+		dated = dated.AddDate(-1, 0, 0)
+	}
+
+	return dated
+}
+
+// ParseTimeLayout converts a custom time layout, either a Go layout such as
+// "02/01/2006 15:04" or a strftime format such as "%d/%m/%Y %H:%M", to a Go
+// layout
+func ParseTimeLayout(layout string) (string, error) {
+	if strings.Contains(layout, "%") {
+		return strftimeLayout(layout)
+	}
+
+	return layout, nil
+}
+
+// parseTime parses a time with the custom TimeLayouts before the built-in
+// ones
+func (f *Formatter) parseTime(value interface{}) (time.Time, bool) {
+	if text, ok := value.(string); ok {
+		for _, layout := range f.opts.TimeLayouts {
+			if parsed, err := time.Parse(layout, strings.TrimSpace(text)); err == nil {
+				return parsed, true
+			}
+		}
+	}
+
+	return ParseTime(value)
+}
@@ -0,0 +1,144 @@
+package logparser
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTimeEpochs(t *testing.T) {
+	want := time.Date(2025, time.June, 15, 8, 18, 2, 337000000, time.UTC)
+
+	tests := []struct {
+		name  string
+		input interface{}
+		want  time.Time
+	}{
+		{"seconds", float64(1749975482), want.Truncate(time.Second)},
+		{"fractional seconds", 1749975482.337, want},
+		{"milliseconds", float64(1749975482337), want},
+		{"microseconds", float64(1749975482337000), want},
+		{"nanoseconds", int64(1749975482337000000), want},
+		{"int milliseconds", 1749975482337, want},
+		{"seconds string", "1749975482", want.Truncate(time.Second)},
+		{"fractional seconds string", "1749975482.337", want},
+		{"nanoseconds string", "1749975482337000000", want},
+		{"json number", json.Number("1749975482337"), want},
+		{"limit of seconds", float64(1e10), time.Unix(1e10, 0)},
+		{"above the limit", float64(1e10 + 1), time.Unix(0, (1e10+1)*int64(time.Millisecond))},
+		{"milliseconds past int64 nanoseconds", int64(9_300_000_000_000), time.UnixMilli(9_300_000_000_000)},
+		{"limit of milliseconds", float64(1e13), time.UnixMilli(1e13)},
+		{"fractional milliseconds past int64 nanoseconds", 9_300_000_000_000.5, time.UnixMilli(9_300_000_000_000).Add(500 * time.Microsecond)},
+		{"microseconds past int64 nanoseconds", int64(9_300_000_000_000_000), time.UnixMicro(9_300_000_000_000_000)},
+		{"limit of microseconds", float64(1e16), time.UnixMicro(1e16)},
+		{"nanoseconds above the limit of microseconds", int64(2e16), time.Unix(0, 2e16)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseTime(tt.input)
+			if !ok {
+				t.Fatalf("ParseTime(%v) failed", tt.input)
+			}
+
+			// Float conversion may lose a little below the millisecond
+			if diff := got.Sub(tt.want); diff < -time.Microsecond || diff > time.Microsecond {
+				t.Errorf("ParseTime(%v) = %v, want %v", tt.input, got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimeStrings(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2024-03-01T12:00:00Z", "2024-03-01T12:00:00Z"},
+		{"2024-03-01T12:00:00.123456789+02:00", "2024-03-01T10:00:00.123456789Z"},
+		{"2024-03-01T12:00:00.5+0100", "2024-03-01T11:00:00.5Z"},
+		{"2024-03-01T12:00:00.123", "2024-03-01T12:00:00.123Z"},
+		{"2024-03-01 12:00:00", "2024-03-01T12:00:00Z"},
+		{"2024-03-01 12:00:00.25", "2024-03-01T12:00:00.25Z"},
+		{"2024-03-01 12:00:00,250", "2024-03-01T12:00:00.25Z"},
+		{"2024-03-01 12:00:00+01:00", "2024-03-01T11:00:00Z"},
+		{"2024-03-01 12:00:00 +0100", "2024-03-01T11:00:00Z"},
+		{"2024-03-01 12:00:00.123 +0000 UTC", "2024-03-01T12:00:00.123Z"},
+		{"2024/03/01 12:00:00", "2024-03-01T12:00:00Z"},
+		{"01/Mar/2024:12:00:00 +0100", "2024-03-01T11:00:00Z"},
+		{"Fri, 01 Mar 2024 12:00:00 GMT", "2024-03-01T12:00:00Z"},
+		{"Fri, 01 Mar 2024 12:00:00 -0500", "2024-03-01T17:00:00Z"},
+		{"Fri Mar  1 12:00:00 2024", "2024-03-01T12:00:00Z"},
+		{"2024-03-01", "2024-03-01T00:00:00Z"},
+		{" 2024-03-01T12:00:00Z ", "2024-03-01T12:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := ParseTime(tt.input)
+			if !ok {
+				t.Fatalf("ParseTime(%q) failed", tt.input)
+			}
+
+			if formatted := got.UTC().Format(time.RFC3339Nano); formatted != tt.want {
+				t.Errorf("ParseTime(%q) = %s, want %s", tt.input, formatted, tt.want)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "invalid-time", "12:00", "1.2.3", "-"} {
+		if got, ok := ParseTime(input); ok {
+			t.Errorf("ParseTime(%q) = %v, expected it to fail", input, got)
+		}
+	}
+}
+
+func TestWithRecentYear(t *testing.T) {
+	now := time.Date(2024, time.January, 2, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"Jan  1 23:59:59", 2024},
+		{"Jan  3 09:00:00", 2024},
+		{"Dec 31 23:59:59", 2023},
+	}
+
+	for _, tt := range tests {
+		parsed, err := time.Parse(time.Stamp, tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := withRecentYear(parsed, now).Year(); got != tt.want {
+			t.Errorf("withRecentYear(%q) year = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+
+	if _, ok := ParseTime("Mar  1 12:00:00"); !ok {
+		t.Error("ParseTime() failed on a syslog time")
+	}
+}
+
+func TestFormatterTimeLayouts(t *testing.T) {
+	layout, err := ParseTimeLayout("%d.%m.%Y %H:%M")
+	if err != nil {
+		t.Fatalf("ParseTimeLayout() error: %v", err)
+	}
+
+	formatter := NewFormatter(Options{TimeLayouts: []string{layout}})
+
+	result, err := formatter.Format(`{"time":"01.03.2024 12:30","msg":"custom"}`)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+
+	if expected := "2024-03-01 12:30:00 custom"; result != expected {
+		t.Errorf("Format() = %q, want %q", result, expected)
+	}
+
+	if _, err := ParseTimeLayout("%Q"); err == nil {
+		t.Error("ParseTimeLayout(%Q) expected an error")
+	}
+}
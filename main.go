@@ -1,20 +1,31 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
-
+	"text/template"
+	"time"
+	// Embedded zone data lets --tz work where the system has none, such as
+	// minimal containers
+	_ "time/tzdata"
+
+	"github.com/dougalmatthews/glug/internal/config"
+	"github.com/dougalmatthews/glug/internal/filter"
+	"github.com/dougalmatthews/glug/internal/processor"
 	"github.com/dougalmatthews/glug/internal/version"
 	"github.com/dougalmatthews/glug/logparser"
+	"github.com/mattn/go-isatty"
+	"golang.org/x/term"
 )
 
+// colorFlags collects --colour rules. The color modes auto, always and never
+// are rejected rather than read as rules, pointing at --color-mode instead.
 type colorFlags []string
 
 func (c *colorFlags) String() string {
@@ -22,79 +33,426 @@ func (c *colorFlags) String() string {
 }
 
 func (c *colorFlags) Set(value string) error {
+	if _, err := logparser.ParseColorMode(value); err == nil && value != "" {
+		return fmt.Errorf("%q is a color mode, not a color rule: use --color-mode %s", value, value)
+	}
+
 	*c = append(*c, value)
+
+	return nil
+}
+
+// listFlags collects the values of a flag that may be repeated
+type listFlags []string
+
+func (l *listFlags) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *listFlags) Set(value string) error {
+	*l = append(*l, value)
 	return nil
 }
 
-// detectPager finds the best available pager
-func detectPager() string {
-	// Check for common pagers in order of preference
-	pagers := []string{"less", "more", "cat"}
+// resolveColorMode decides whether to color output. An explicit always or
+// never wins; in auto mode FORCE_COLOR and CLICOLOR_FORCE turn color on (or
+// off, when FORCE_COLOR is 0 or false), NO_COLOR turns it off, and otherwise
+// output is colored when it goes to a terminal.
+func resolveColorMode(name string, lookupEnv func(string) (string, bool), terminal bool) (logparser.ColorMode, error) {
+	mode, err := logparser.ParseColorMode(name)
+	if err != nil || mode != logparser.ColorAuto {
+		return mode, err
+	}
+
+	if value, ok := lookupEnv("FORCE_COLOR"); ok {
+		if value == "0" || strings.EqualFold(value, "false") {
+			return logparser.ColorNever, nil
+		}
+
+		return logparser.ColorAlways, nil
+	}
+
+	if value, ok := lookupEnv("CLICOLOR_FORCE"); ok && value != "" && value != "0" {
+		return logparser.ColorAlways, nil
+	}
+
+	if value, ok := lookupEnv("NO_COLOR"); ok && value != "" {
+		return logparser.ColorNever, nil
+	}
+
+	if value, _ := lookupEnv("TERM"); !terminal || value == "dumb" {
+		return logparser.ColorNever, nil
+	}
+
+	return logparser.ColorAlways, nil
+}
+
+// isTerminal reports whether f is connected to a terminal
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// terminalWidth returns the width of the terminal stdout is connected to,
+// falling back to $COLUMNS and then 0 when it is unknown
+func terminalWidth() int {
+	if isTerminal(os.Stdout) {
+		if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+
+	return 0
+}
+
+// flagsSet returns the names of the flags given on the command line
+func flagsSet() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	return set
+}
+
+// loadConfig finds and loads the config file, returning its path
+func loadConfig(configPath string) (*config.File, string, error) {
+	path, err := config.Find(configPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	file, err := config.Load(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return file, path, nil
+}
+
+// showConfig prints the effective settings as TOML
+func showConfig(settings *config.File, path string) error {
+	if path == "" {
+		fmt.Println("# No config file found")
+	} else {
+		fmt.Printf("# Loaded from %s\n", path)
+	}
+
+	return settings.Write(os.Stdout)
+}
+
+// resolveTheme returns the theme called name: one of the user-defined themes
+// from the config file, or else a built-in theme
+func resolveTheme(name string, themes map[string]map[string]string) (*logparser.Theme, error) {
+	if name == "" {
+		name = logparser.DefaultThemeName
+	}
+
+	if specs, ok := themes[name]; ok {
+		theme, err := logparser.ParseTheme(specs)
+		if err != nil {
+			return nil, fmt.Errorf("theme %s: %v", name, err)
+		}
+
+		return theme, nil
+	}
+
+	return logparser.BuiltinTheme(name)
+}
+
+// processorConfig converts settings into the formatting and filtering
+// options of a processor config, looking themes up in themes
+func processorConfig(settings *config.Settings, themes map[string]map[string]string) (*processor.Config, error) {
+	// Later rules win where matches overlap, so the command line beats the
+	// config file and highlight rules beat color rules
+	var highlights []logparser.HighlightRule
+
+	for _, rule := range settings.Colors {
+		parts := strings.SplitN(rule, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid color rule format: %s (expected color:word)", rule)
+		}
+
+		color, word := parts[0], parts[1]
+		if _, err := logparser.ParseStyle(color); err != nil {
+			return nil, fmt.Errorf("invalid color rule %s: %v", rule, err)
+		}
+
+		highlight, err := logparser.ParseHighlightRule(color + "=text:" + word)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color rule %s: %v", rule, err)
+		}
+
+		highlights = append(highlights, highlight)
+	}
+
+	for _, spec := range settings.Highlight {
+		rule, err := logparser.ParseHighlightRule(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		highlights = append(highlights, rule)
+	}
+
+	var fieldColors []logparser.FieldColorRule
+
+	for _, spec := range settings.FieldColors {
+		rule, err := logparser.ParseFieldColorRule(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		fieldColors = append(fieldColors, rule)
+	}
+
+	timeFormat, err := logparser.ParseTimeFormat(settings.TimeFormat, settings.TimePrecision)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeLayouts []string
+
+	for _, spec := range settings.TimeLayouts {
+		layout, err := logparser.ParseTimeLayout(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		timeLayouts = append(timeLayouts, layout)
+	}
+
+	// "auto" among the timestamp fields turns on detection
+	var (
+		timestampFields []string
+		autoTimestamps  bool
+	)
+
+	for _, field := range settings.TimestampFields {
+		if strings.EqualFold(field, "auto") {
+			autoTimestamps = true
+		} else {
+			timestampFields = append(timestampFields, field)
+		}
+	}
+
+	var location *time.Location
+	if settings.TimeZone != "" {
+		location, err = logparser.ParseLocation(settings.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	theme, err := resolveTheme(settings.Theme, themes)
+	if err != nil {
+		return nil, err
+	}
+
+	var where *filter.Expr
+	if settings.Where != "" {
+		expr, err := filter.Parse(settings.Where)
+		if err != nil {
+			return nil, fmt.Errorf("invalid where expression: %v", err)
+		}
+
+		where = expr
+	}
+
+	levelScheme, err := logparser.ParseLevelScheme(settings.LevelScheme)
+	if err != nil {
+		return nil, fmt.Errorf("invalid level scheme: %s (expected auto, bunyan, pino or syslog)", settings.LevelScheme)
+	}
+
+	return &processor.Config{
+		MinLevel:    settings.Level,
+		PagerCmd:    settings.PagerCmd,
+		Highlights:  highlights,
+		FieldColors: fieldColors,
+		HashFields:  settings.HashColors,
+		// Timestamp conversion is enabled only if fields are specified
+		ConvertTimestamps:  autoTimestamps || len(timestampFields) > 0,
+		TimestampFieldList: timestampFields,
+		AutoTimestamps:     autoTimestamps,
+		TimestampExclude:   settings.TimestampExclude,
+		Fields: logparser.FieldMapping{
+			Level:   settings.Fields.Level,
+			Time:    settings.Fields.Time,
+			Message: settings.Fields.Message,
+		},
+		LevelScheme: levelScheme,
+		Where:       where,
+		ModulePaths: settings.ModulePaths,
+		HideFields:  settings.Hide,
+		Theme:       theme,
+		TimeFormat:  timeFormat,
+		Location:    location,
+		TimeLayouts: timeLayouts,
+	}, nil
+}
+
+// autoProfiles builds the profiles that select themselves with a match
+// expression. Each is layered between the config file and the command line.
+func autoProfiles(file *config.File, cli *config.Settings) ([]processor.Profile, error) {
+	var profiles []processor.Profile
+
+	for _, name := range file.ProfileNames() {
+		profile := file.Profiles[name]
+		if profile.Match == "" {
+			continue
+		}
 
-	for _, pager := range pagers {
-		if _, err := exec.LookPath(pager); err == nil {
-			return pager
+		match, err := filter.Parse(profile.Match)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: invalid match expression: %v", name, err)
 		}
+
+		profileConfig, err := processorConfig(file.Merge(&profile.Settings).Merge(cli), file.Themes)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %v", name, err)
+		}
+
+		profiles = append(profiles, processor.Profile{Name: name, Match: match, Config: profileConfig})
 	}
 
-	// Fallback to cat if no pager is found
-	return "cat"
+	return profiles, nil
 }
 
-// executeWithPager runs the pager with the given content
-func executeWithPager(content string, pagerName string) error {
-	var cmd *exec.Cmd
-
-	// Configure pager with appropriate flags for color support
-	switch pagerName {
-	case "less":
-		// -R: enable raw control characters (colors)
-		// -X: don't clear screen on exit
-		// -F: quit if one screen
-		cmd = exec.Command("less", "-R", "-X", "-F")
-	case "more":
-		// more doesn't need special flags for colors
-		cmd = exec.Command("more")
-	case "cat":
-		// cat just outputs everything
-		cmd = exec.Command("cat")
-	default:
-		cmd = exec.Command(pagerName)
-	}
-
-	// Set up stdin for the pager
-	cmd.Stdin = strings.NewReader(content)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	// Start the pager
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start pager %s: %v", pagerName, err)
-	}
-
-	// Wait for the pager to complete
-	return cmd.Wait()
+// profileConfigs returns the configs of profiles
+func profileConfigs(profiles []processor.Profile) []*processor.Config {
+	configs := make([]*processor.Config, len(profiles))
+	for i, profile := range profiles {
+		configs[i] = profile.Config
+	}
+
+	return configs
 }
 
 func main() {
 	var colorRules colorFlags
-	flag.Var(&colorRules, "colour", "Color specific words (format: color:word, e.g., green:PASS)")
-	flag.Var(&colorRules, "color", "Color specific words (format: color:word, e.g., green:PASS)")
+	flag.Var(&colorRules, "colour", "Color specific words (format: style:word, e.g., green:PASS or 'bold #ff8800:SLOW')")
+	flag.Var(&colorRules, "color", "Color specific words (format: style:word, e.g., green:PASS or 'bold #ff8800:SLOW')")
+
+	var colorMode string
+	flag.StringVar(&colorMode, "color-mode", "", "Color output: auto, always or never; auto honors NO_COLOR, FORCE_COLOR and CLICOLOR_FORCE (default: auto)")
+	flag.StringVar(&colorMode, "colour-mode", "", "Color output: auto, always or never; auto honors NO_COLOR, FORCE_COLOR and CLICOLOR_FORCE (default: auto)")
+
+	var highlightRules listFlags
+	flag.Var(&highlightRules, "highlight", "Highlight matching text: [style=][re|text|word[/iw]:]pattern, e.g. 're:user_id=(\\d+)' or 'red=word/i:timeout' (repeatable)")
+
+	var fieldColorRules listFlags
+	flag.Var(&fieldColorRules, "colour-field", "Color a field's value when it matches: field[op value]:style with op =, !=, >, >=, <, <= or a low..high range, e.g. status=500:red or latency>250ms:yellow (repeatable)")
+	flag.Var(&fieldColorRules, "color-field", "Color a field's value when it matches: field[op value]:style with op =, !=, >, >=, <, <= or a low..high range, e.g. status=500:red or latency>250ms:yellow (repeatable)")
+
+	var hashColors string
+	flag.StringVar(&hashColors, "hash-color", "", "Comma-separated list of fields whose values each get a stable color of their own (e.g. request_id,pod)")
+	flag.StringVar(&hashColors, "hash-colour", "", "Comma-separated list of fields whose values each get a stable color of their own (e.g. request_id,pod)")
+
+	var timeZone string
+	flag.StringVar(&timeZone, "tz", "", "Time zone to show times in: UTC, Local or a name such as Europe/London (default: as logged)")
+
+	var timeFormat string
+	flag.StringVar(&timeFormat, "time-format", "", "Time format: "+strings.Join(logparser.TimeFormatNames(), ", ")+", a strftime format such as '%H:%M:%S' or a Go layout")
+
+	var timeLayouts listFlags
+	flag.Var(&timeLayouts, "time-layout", "Extra layout to parse times with, as a Go layout or strftime format, e.g. '%d/%m/%Y %H:%M' (repeatable)")
+
+	var timePrecision string
+	flag.StringVar(&timePrecision, "time-precision", "", "Precision of shown times: s, ms, us or ns")
+
+	var themeName string
+	flag.StringVar(&themeName, "theme", "", "Color theme: dark, light, solarized, high-contrast or a theme from the config file (default: dark)")
 
 	var minLevel string
-	flag.StringVar(&minLevel, "level", "", "Minimum log level to show (trace, debug, info, warn/warning, error)")
+	flag.StringVar(&minLevel, "level", "", "Minimum log level to show (trace, debug, info, notice, warn/warning, error, critical, fatal, panic)")
 
 	var usePager bool
 	flag.BoolVar(&usePager, "pager", true, "Use pager for output (auto-detects less/more) [default: true]")
 	flag.BoolVar(&usePager, "p", true, "Use pager for output (auto-detects less/more) [default: true]")
 
+	var pagerCmd string
+	flag.StringVar(&pagerCmd, "pager-cmd", "", "Pager command line, e.g. 'less -S' or 'bat --paging=always' (default: $GLUG_PAGER, $PAGER, then less/more)")
+
 	var noPager bool
 	flag.BoolVar(&noPager, "no-pager", false, "Disable pager (output directly to stdout)")
 	flag.BoolVar(&noPager, "n", false, "Disable pager (output directly to stdout)")
 
 	var timestampFields string
-	flag.StringVar(&timestampFields, "convert-timestamps", "", "Comma-separated list of field names to convert as timestamps")
-	flag.StringVar(&timestampFields, "t", "", "Comma-separated list of field names to convert as timestamps")
+	flag.StringVar(&timestampFields, "convert-timestamps", "", "Comma-separated list of field names to convert as timestamps, or auto to detect them")
+	flag.StringVar(&timestampFields, "t", "", "Comma-separated list of field names to convert as timestamps, or auto to detect them")
+
+	var timestampExclude string
+	flag.StringVar(&timestampExclude, "timestamp-exclude", "", "Comma-separated list of fields never converted by --convert-timestamps=auto")
+
+	var follow bool
+	flag.BoolVar(&follow, "follow", false, "Keep reading files as they grow, surviving truncation and rotation")
+	flag.BoolVar(&follow, "f", false, "Keep reading files as they grow, surviving truncation and rotation")
+
+	var merge bool
+	flag.BoolVar(&merge, "merge", false, "Merge multiple files into one stream ordered by timestamp")
+	flag.BoolVar(&merge, "m", false, "Merge multiple files into one stream ordered by timestamp")
+
+	var sourceTags bool
+	flag.BoolVar(&sourceTags, "source-tag", false, "Prefix each line with a colored tag naming its source file")
+
+	var whereExpr string
+	flag.StringVar(&whereExpr, "where", "", "Only show entries matching a field expression (e.g. 'status >= 500 && has(error)')")
+	flag.StringVar(&whereExpr, "w", "", "Only show entries matching a field expression (e.g. 'status >= 500 && has(error)')")
+
+	var levelKeys, timeKeys, messageKeys string
+	flag.StringVar(&levelKeys, "level-key", "", "Comma-separated list of keys holding the log level (default: level,severity,lvl,loglevel)")
+	flag.StringVar(&timeKeys, "time-key", "", "Comma-separated list of keys holding the timestamp (default: time,ts,timestamp,@timestamp)")
+	flag.StringVar(&messageKeys, "message-key", "", "Comma-separated list of keys holding the message (default: message,msg,@message)")
+
+	var levelSchemeName string
+	flag.StringVar(&levelSchemeName, "level-scheme", "auto", "How to decode numeric levels (auto, bunyan/pino, syslog)")
+
+	var flattenDepth int
+	flag.IntVar(&flattenDepth, "flatten-depth", 0, "Maximum number of segments in the dotted keys nested objects are flattened into (0 = no limit, 1 = don't flatten)")
+
+	var expand bool
+	flag.BoolVar(&expand, "expand", false, "Show objects, nested arrays and multi-line strings on indented lines below each entry")
+
+	var expandFields string
+	flag.StringVar(&expandFields, "expand-field", "", "Comma-separated list of fields to always show on indented lines below each entry (e.g. stack,error.trace)")
+
+	var modulePaths string
+	flag.StringVar(&modulePaths, "module-path", "", "Comma-separated module or package prefixes whose stack trace frames are highlighted (e.g. github.com/acme,com.acme)")
+
+	var outputName string
+	flag.StringVar(&outputName, "output", "text", "Output format (text, table, logfmt, json, csv, tsv, template)")
+	flag.StringVar(&outputName, "o", "text", "Output format (text, table, logfmt, json, csv, tsv, template)")
+
+	var columns string
+	flag.StringVar(&columns, "columns", "", "Comma-separated list of columns for csv and tsv output (default: time,level,message)")
+
+	var templateText string
+	flag.StringVar(&templateText, "template", "", "Go text/template for each entry, e.g. '{{.Time}} [{{.Level}}] {{.Message}} {{.Fields.caller}}' (implies --output template)")
+
+	var showFields string
+	flag.StringVar(&showFields, "fields", "", "Comma-separated list of fields to show, in order (e.g. caller,subsystem)")
+
+	var hideFields string
+	flag.StringVar(&hideFields, "hide", "", "Comma-separated list of fields to hide (e.g. password,token,program)")
+
+	var fieldsFirst bool
+	flag.BoolVar(&fieldsFirst, "fields-first", false, "Show fields before the message")
+
+	var width int
+	flag.IntVar(&width, "width", 0, "Width to fit table rows to (default: the terminal width, or $COLUMNS)")
+
+	var wrap bool
+	flag.BoolVar(&wrap, "wrap", false, "Wrap long table rows onto indented lines instead of truncating them")
+
+	var columnWidth int
+	flag.IntVar(&columnWidth, "column-width", logparser.DefaultColumnWidth, "Width of the field columns chosen with --fields in table output")
+
+	var profileName string
+	flag.StringVar(&profileName, "profile", "", "Apply a named profile from the config file (default: select profiles by their match expression)")
+
+	var configPath string
+	flag.StringVar(&configPath, "config", "", "Config file to load (default: $GLUG_CONFIG, then glug/config.toml in $XDG_CONFIG_HOME or $XDG_CONFIG_DIRS)")
 
 	var help bool
 	flag.BoolVar(&help, "help", false, "Show help message")
@@ -106,6 +464,14 @@ func main() {
 
 	flag.Parse()
 
+	// "glug config show" prints the effective config; flags may follow it
+	showConfigCmd := flag.NArg() >= 2 && flag.Arg(0) == "config" && flag.Arg(1) == "show"
+	if showConfigCmd {
+		if err := flag.CommandLine.Parse(flag.Args()[2:]); err != nil {
+			os.Exit(2)
+		}
+	}
+
 	if showVersion {
 		fmt.Println(version.Get().String())
 		return
@@ -113,7 +479,9 @@ func main() {
 
 	if help {
 		fmt.Fprintf(os.Stderr, "Glug - JSON Log Parser and Colorizer\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: glug [options] < logfile.json\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: glug [options] [file ...]\n")
+		fmt.Fprintf(os.Stderr, "       glug [options] < logfile.json\n")
+		fmt.Fprintf(os.Stderr, "       glug config show [options]\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
@@ -122,134 +490,231 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  docker logs container | glug --level warning --color red:ERROR\n")
 		fmt.Fprintf(os.Stderr, "  cat large-logs.json | glug --level error\n")
 		fmt.Fprintf(os.Stderr, "  echo '{\"message\":\"Quick output\"}' | glug --no-pager\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --pager-cmd 'less -S'\n")
 		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --convert-timestamps validUntil,expires\n")
 		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --convert-timestamps created,updated\n")
-		fmt.Fprintf(os.Stderr, "\nSupported colors: red, green, yellow, blue, magenta, cyan, white\n")
-		fmt.Fprintf(os.Stderr, "Supported levels: trace, debug, info, warn/warning, error\n")
-		fmt.Fprintf(os.Stderr, "Pager: Enabled by default, use --no-pager to disable\n")
-		fmt.Fprintf(os.Stderr, "Timestamps: Use --convert-timestamps to specify which fields to convert\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --convert-timestamps=auto --timestamp-exclude since\n")
+		fmt.Fprintf(os.Stderr, "  glug app.log worker.log\n")
+		fmt.Fprintf(os.Stderr, "  glug --follow /var/log/app/current.log\n")
+		fmt.Fprintf(os.Stderr, "  glug --merge --source-tag api.log worker.log db.log\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --where 'subsystem == \"secretstore\" && status >= 500'\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --where 'caller =~ \"tenant.go\" || has(error)'\n")
+		fmt.Fprintf(os.Stderr, "  cat gcp.json | glug --level-key severity --message-key textPayload\n")
+		fmt.Fprintf(os.Stderr, "  node app.js | glug --level-scheme pino --level warn\n")
+		fmt.Fprintf(os.Stderr, "  glug config show --level debug\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --where 'http.status >= 500' --flatten-depth 2\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --expand-field stack,error.trace\n")
+		fmt.Fprintf(os.Stderr, "  cat errors.json | glug --module-path github.com/acme\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --fields caller,subsystem --fields-first\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --output table --fields caller --wrap\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --hide password,token,program\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --output logfmt --level-key severity\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --output csv --columns time,level,message,caller > logs.csv\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --template '{{.Time}} [{{.Level}}] {{.Message}} {{.Fields.caller}}'\n")
+		fmt.Fprintf(os.Stderr, "  cat gateway.json | glug --profile gateway\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --theme solarized --colour 'bold #ff8800:SLOW'\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --color-mode always --no-pager > colored.log\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --highlight 're:user_id=(\\d+)' --highlight 'bold red=word/i:timeout'\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --colour-field status=500..599:red --colour-field 'latency>250ms:yellow'\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --hash-color request_id,pod\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --tz UTC --time-format iso --time-precision ms\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --time-format delta\n")
+		fmt.Fprintf(os.Stderr, "  cat logs.json | glug --time-layout '%%d/%%m/%%Y %%H:%%M'\n")
+		fmt.Fprintf(os.Stderr, "\nSupported colors: red, green, yellow, blue, magenta, cyan, white, black, gray, hi-<color>, 0-255 and #rrggbb\n")
+		fmt.Fprintf(os.Stderr, "Highlight: later rules win where matches overlap; with capture groups only the groups are colored\n")
+		fmt.Fprintf(os.Stderr, "Color mode: --color-mode auto|always|never; auto honors NO_COLOR, FORCE_COLOR and CLICOLOR_FORCE\n")
+		fmt.Fprintf(os.Stderr, "Styles: colors combined with bold, faint, italic, underline, reverse and 'on <color>' backgrounds\n")
+		fmt.Fprintf(os.Stderr, "Supported levels: trace, debug, info, notice, warn/warning, error, critical, fatal, panic\n")
+		fmt.Fprintf(os.Stderr, "Numeric levels: auto decodes 0-7 as syslog and 10-60 as Bunyan/Pino\n")
+		fmt.Fprintf(os.Stderr, "Pager: Enabled by default when writing to a terminal, use --no-pager to disable\n")
+		fmt.Fprintf(os.Stderr, "       Chosen from --pager-cmd, $GLUG_PAGER, $PAGER, then less/more\n")
+		fmt.Fprintf(os.Stderr, "Timestamps: Use --convert-timestamps to specify which fields to convert, or auto to convert fields named like timestamps with plausible values\n")
+		fmt.Fprintf(os.Stderr, "Where: compare fields with == != < <= > >= =~ !~, combine with && || ! and test presence with has(field)\n")
+		fmt.Fprintf(os.Stderr, "Fields: level, time and message keys from zap, logrus, slog, Bunyan and GCP are recognized by default\n")
+		fmt.Fprintf(os.Stderr, "Table: time, level and --fields are padded to fixed widths, the rest is cut to the terminal width\n")
+		fmt.Fprintf(os.Stderr, "Output: json and logfmt write normalized time, level and message keys; templates get .Time, .Level, .Message and .Fields\n")
+		fmt.Fprintf(os.Stderr, "Traces: Go, Java and Python stack traces in any field are shown frame by frame below the entry\n")
+		fmt.Fprintf(os.Stderr, "Nested: nested objects are shown as dotted keys (http.status=500), which --where and field keys also accept\n")
+		fmt.Fprintf(os.Stderr, "Config: settings are read from ~/.config/glug/config.toml, command-line flags take precedence\n")
+		fmt.Fprintf(os.Stderr, "Themes: [themes.<name>] sections in the config file map roles such as time, key and level_error to styles\n")
+		fmt.Fprintf(os.Stderr, "Profiles: [profiles.<name>] sections apply with --profile, or to entries matching their match expression\n")
 
 		return
 	}
 
-	// Parse color rules
-	customColors := make(map[string]string)
+	// Only flags given on the command line override the config file
+	set := flagsSet()
+	cli := &config.Settings{
+		Level:            minLevel,
+		Colors:           colorRules,
+		Highlight:        highlightRules,
+		FieldColors:      fieldColorRules,
+		HashColors:       logparser.ParseFieldKeys(hashColors),
+		ColorMode:        colorMode,
+		Theme:            themeName,
+		TimeZone:         timeZone,
+		TimeFormat:       timeFormat,
+		TimePrecision:    timePrecision,
+		TimeLayouts:      timeLayouts,
+		PagerCmd:         pagerCmd,
+		Where:            whereExpr,
+		ModulePaths:      logparser.ParseFieldKeys(modulePaths),
+		Hide:             logparser.ParseFieldKeys(hideFields),
+		TimestampExclude: logparser.ParseFieldKeys(timestampExclude),
+		Fields: config.Fields{
+			Level:   logparser.ParseFieldKeys(levelKeys),
+			Time:    logparser.ParseFieldKeys(timeKeys),
+			Message: logparser.ParseFieldKeys(messageKeys),
+		},
+	}
 
-	for _, rule := range colorRules {
-		parts := strings.SplitN(rule, ":", 2)
-		if len(parts) != 2 {
-			fmt.Fprintf(os.Stderr, "Invalid color rule format: %s (expected color:word)\n", rule)
-			os.Exit(1)
-		}
+	if set["level-scheme"] {
+		cli.LevelScheme = levelSchemeName
+	}
 
-		color, word := parts[0], parts[1]
-		customColors[word] = color
+	if set["convert-timestamps"] || set["t"] {
+		cli.TimestampFields = logparser.ParseFieldKeys(timestampFields)
+	}
+
+	if set["pager"] || set["p"] {
+		cli.Pager = &usePager
 	}
 
-	// Handle pager logic: default to true, but can be disabled with --no-pager
 	if noPager {
 		usePager = false
+		cli.Pager = &usePager
 	}
 
-	// Parse timestamp fields - conversion is enabled only if fields are specified
-	var (
-		timestampFieldList []string
-		convertTimestamps  bool
-	)
-	if timestampFields != "" {
-		convertTimestamps = true
-		timestampFieldList = strings.Split(timestampFields, ",")
-		// Trim whitespace from each field name
-		for i, field := range timestampFieldList {
-			timestampFieldList[i] = strings.TrimSpace(field)
+	file, configFile, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config: %v\n", err)
+		os.Exit(1)
+	}
+
+	base := &file.Settings
+	if profileName != "" {
+		profile, err := file.Profile(profileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid profile: %v\n", err)
+			os.Exit(1)
 		}
+
+		base = base.Merge(profile)
 	}
 
-	// Set up signal handling for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	settings := base.Merge(cli)
 
-	go func() {
-		<-sigChan
-		cancel()
-	}()
+	if showConfigCmd {
+		if err := showConfig(&config.File{Settings: *settings, Profiles: file.Profiles, Themes: file.Themes}, configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 
-	scanner := bufio.NewScanner(os.Stdin)
+		return
+	}
 
-	// Collect output if using pager
-	var outputLines []string
+	if merge && follow {
+		fmt.Fprintf(os.Stderr, "--merge cannot be combined with --follow\n")
+		os.Exit(1)
+	}
 
-	for scanner.Scan() {
-		// Check if we should exit due to signal
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
+	cfg, err := processorConfig(settings, file.Themes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
-		line := scanner.Text()
-		if line == "" {
-			continue
+	// An explicit --profile replaces automatic selection
+	if profileName == "" {
+		cfg.Profiles, err = autoProfiles(file, cli)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid config: %v\n", err)
+			os.Exit(1)
 		}
+	}
 
-		formatted, err := logparser.ParseAndFormatWithOptions(line, customColors, convertTimestamps, timestampFieldList)
-		if err != nil {
-			// If parsing fails, just print the original line
-			if usePager {
-				outputLines = append(outputLines, line)
-			} else {
-				fmt.Println(line)
-			}
+	if flattenDepth < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid flatten depth: %d (expected 0 or more)\n", flattenDepth)
+		os.Exit(1)
+	}
 
-			continue
-		}
+	if width < 0 || columnWidth < 1 {
+		fmt.Fprintf(os.Stderr, "Invalid table width: --width must be 0 or more and --column-width 1 or more\n")
+		os.Exit(1)
+	}
 
-		// Apply level filtering if specified
-		if minLevel != "" {
-			shouldShow, err := logparser.ShouldShowLogLevel(line, minLevel)
-			if err != nil {
-				// If level parsing fails, show the line (fail open)
-				if usePager {
-					outputLines = append(outputLines, formatted)
-				} else {
-					fmt.Println(formatted)
-				}
+	if width == 0 {
+		width = terminalWidth()
+	}
 
-				continue
-			}
+	if templateText != "" && !set["output"] && !set["o"] {
+		outputName = "template"
+	}
 
-			if !shouldShow {
-				continue
-			}
-		}
+	output, err := logparser.ParseOutputFormat(outputName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid output format: %s (expected text, table, logfmt, json, csv, tsv or template)\n", outputName)
+		os.Exit(1)
+	}
 
-		if usePager {
-			outputLines = append(outputLines, formatted)
-		} else {
-			fmt.Println(formatted)
+	var outputTemplate *template.Template
+	if output == logparser.OutputTemplate {
+		if templateText == "" {
+			fmt.Fprintf(os.Stderr, "--output template requires --template\n")
+			os.Exit(1)
 		}
-	}
 
-	if err := scanner.Err(); err != nil {
-		// Don't report error if context was cancelled (user pressed Ctrl+C)
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+		outputTemplate, err = logparser.ParseTemplate(templateText)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid template: %v\n", err)
 			os.Exit(1)
 		}
 	}
 
-	// If using pager, execute it with collected output
-	if usePager {
-		pagerName := detectPager()
-		content := strings.Join(outputLines, "\n")
+	// Pager output is colored like direct output, since the pager shows it on
+	// the same terminal
+	resolvedColor, err := resolveColorMode(settings.ColorMode, os.LookupEnv, isTerminal(os.Stdout))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid color mode: %v\n", err)
+		os.Exit(1)
+	}
 
-		if err := executeWithPager(content, pagerName); err != nil {
-			fmt.Fprintf(os.Stderr, "Error running pager: %v\n", err)
-			os.Exit(1)
-		}
+	for _, c := range append([]*processor.Config{cfg}, profileConfigs(cfg.Profiles)...) {
+		c.Color = resolvedColor
+		c.FlattenDepth = flattenDepth
+		c.Expand = expand
+		c.ExpandFields = logparser.ParseFieldKeys(expandFields)
+		c.Output = output
+		c.Columns = logparser.ParseFieldKeys(columns)
+		c.Template = outputTemplate
+		c.ShowFields = logparser.ParseFieldKeys(showFields)
+		c.FieldsFirst = fieldsFirst
+		c.Width = width
+		c.Wrap = wrap
+		c.ColumnWidth = columnWidth
+	}
+
+	// Handle pager logic: default to true, but can be disabled with --no-pager
+	// or in the config file. There is nothing to page when output is going to
+	// a file or another program.
+	cfg.UsePager = settings.UsePager() && isTerminal(os.Stdout)
+	cfg.Files = flag.Args()
+	cfg.Follow = follow
+	cfg.Merge = merge
+	cfg.SourceTags = sourceTags
+
+	// Set up signal handling for graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	if err := processor.NewLogProcessor(cfg).Process(ctx, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 }
@@ -5,6 +5,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/dougalmatthews/glug/logparser"
 )
 
 func TestSignalHandling(t *testing.T) {
@@ -78,35 +80,66 @@ func splitColorRule(rule string) []string {
 	return strings.SplitN(rule, ":", 2)
 }
 
-func TestDetectPager(t *testing.T) {
-	// Test that detectPager returns a valid pager
-	pager := detectPager()
-	if pager == "" {
-		t.Error("detectPager() should return a non-empty string")
-	}
+func TestColorFlagsSet(t *testing.T) {
+	var flags colorFlags
 
-	// Should be one of the expected pagers
-	expectedPagers := []string{"less", "more", "cat"}
-	validPager := false
+	for _, value := range []string{"green:PASS", "red:FAIL"} {
+		if err := flags.Set(value); err != nil {
+			t.Fatalf("Set(%q) error: %v", value, err)
+		}
+	}
 
-	for _, expected := range expectedPagers {
-		if pager == expected {
-			validPager = true
-			break
+	for _, value := range []string{"auto", "always", "never", "Never"} {
+		if err := flags.Set(value); err == nil {
+			t.Errorf("Set(%q) expected an error pointing at --color-mode", value)
 		}
 	}
 
-	if !validPager {
-		t.Errorf("detectPager() returned unexpected pager: %s", pager)
+	if strings.Join(flags, ",") != "green:PASS,red:FAIL" {
+		t.Errorf("rules = %v, want [green:PASS red:FAIL]", flags)
 	}
 }
 
-func TestExecuteWithPager(t *testing.T) {
-	// Test with cat (should always be available)
-	content := "test line 1\ntest line 2\ntest line 3"
+func TestResolveColorMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		env      map[string]string
+		terminal bool
+		expected logparser.ColorMode
+	}{
+		{"terminal", "", nil, true, logparser.ColorAlways},
+		{"pipe", "auto", nil, false, logparser.ColorNever},
+		{"dumb terminal", "", map[string]string{"TERM": "dumb"}, true, logparser.ColorNever},
+		{"always beats NO_COLOR", "always", map[string]string{"NO_COLOR": "1"}, false, logparser.ColorAlways},
+		{"never beats FORCE_COLOR", "never", map[string]string{"FORCE_COLOR": "1"}, true, logparser.ColorNever},
+		{"NO_COLOR", "", map[string]string{"NO_COLOR": "1"}, true, logparser.ColorNever},
+		{"empty NO_COLOR is ignored", "", map[string]string{"NO_COLOR": ""}, true, logparser.ColorAlways},
+		{"FORCE_COLOR", "", map[string]string{"FORCE_COLOR": "1", "NO_COLOR": "1"}, false, logparser.ColorAlways},
+		{"FORCE_COLOR=0", "", map[string]string{"FORCE_COLOR": "0"}, true, logparser.ColorNever},
+		{"CLICOLOR_FORCE", "", map[string]string{"CLICOLOR_FORCE": "1"}, false, logparser.ColorAlways},
+		{"CLICOLOR_FORCE=0", "", map[string]string{"CLICOLOR_FORCE": "0"}, false, logparser.ColorNever},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookupEnv := func(key string) (string, bool) {
+				value, ok := tt.env[key]
+				return value, ok
+			}
+
+			got, err := resolveColorMode(tt.mode, lookupEnv, tt.terminal)
+			if err != nil {
+				t.Fatalf("resolveColorMode() error: %v", err)
+			}
+
+			if got != tt.expected {
+				t.Errorf("resolveColorMode() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
 
-	err := executeWithPager(content, "cat")
-	if err != nil {
-		t.Errorf("executeWithPager() with cat failed: %v", err)
+	if _, err := resolveColorMode("sometimes", os.LookupEnv, true); err == nil {
+		t.Error("resolveColorMode(sometimes) expected an error")
 	}
 }